@@ -0,0 +1,52 @@
+package db
+
+import (
+	"github.com/jackc/pgconn"
+)
+
+// BatchItem is a query queued into a Batch
+type BatchItem struct {
+	Query Query // Query to execute
+	Args  []any // Query arguments
+	Dest  any   // Destination for returned rows, nil for queries executed without scanning
+	One   bool  // Scan a single row into Dest instead of all rows
+}
+
+// Batch collects queries that are sent to the database in a single round trip.
+// The smallest Query.Timeout of the queued queries applies to the whole batch:
+// to every statement via SET LOCAL statement_timeout inside a transaction, via the context outside it.
+type Batch struct {
+	items []BatchItem
+}
+
+// Queue adds a query whose rows, if any, are discarded
+func (b *Batch) Queue(q Query, args ...any) {
+	b.items = append(b.items, BatchItem{Query: q, Args: args})
+}
+
+// QueueScanOne adds a query whose single row is scanned into dest
+func (b *Batch) QueueScanOne(dest any, q Query, args ...any) {
+	b.items = append(b.items, BatchItem{Query: q, Args: args, Dest: dest, One: true})
+}
+
+// QueueScanAll adds a query whose rows are scanned into dest
+func (b *Batch) QueueScanAll(dest any, q Query, args ...any) {
+	b.items = append(b.items, BatchItem{Query: q, Args: args, Dest: dest})
+}
+
+// Items returns queued queries in the order they were added
+func (b *Batch) Items() []BatchItem {
+	return b.items
+}
+
+// Len returns the number of queued queries
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// BatchResult is the outcome of a single query of a batch
+type BatchResult struct {
+	Query      Query             // Executed query
+	CommandTag pgconn.CommandTag // Command tag for queries executed without scanning
+	Err        error             // Query error, nil on success
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"github.com/jackc/pgx/v4"
+)
+
+// CopyQuery describes a bulk load into a table via the COPY protocol
+type CopyQuery struct {
+	Name    string         // Name of the operation used in logs and traces
+	Table   pgx.Identifier // Target table, optionally schema qualified
+	Columns []string       // Target columns in the order values appear in source rows
+}
+
+// CopyFromRows returns a source over rows that are already in memory
+func CopyFromRows(rows [][]any) pgx.CopyFromSource {
+	return pgx.CopyFromRows(rows)
+}
+
+// CopyFromSlice returns a source of length rows, where next builds the i-th row on demand
+func CopyFromSlice(length int, next func(i int) ([]any, error)) pgx.CopyFromSource {
+	return pgx.CopyFromSlice(length, next)
+}
+
+// CopyFromFunc returns a streaming source, next reports ok=false once rows are exhausted
+func CopyFromFunc(next func() (row []any, ok bool, err error)) pgx.CopyFromSource {
+	return &copyFromFunc{next: next}
+}
+
+type copyFromFunc struct {
+	next func() ([]any, bool, error)
+	row  []any
+	err  error
+}
+
+func (c *copyFromFunc) Next() bool {
+	if c.err != nil {
+		return false
+	}
+
+	row, ok, err := c.next()
+	if err != nil {
+		c.err = err
+		return false
+	}
+
+	c.row = row
+	return ok
+}
+
+func (c *copyFromFunc) Values() ([]any, error) {
+	return c.row, nil
+}
+
+func (c *copyFromFunc) Err() error {
+	return c.err
+}
@@ -2,9 +2,12 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
 )
 
 // Handler defines a function type that executes within a database transaction.
@@ -12,9 +15,21 @@ import (
 // database operations that need to be executed atomically as part of a transaction.
 type Handler func(ctx context.Context) error
 
-// TxManager is a transaction manager that executes a user-specified handler within a transaction
+// TxManager is a transaction manager that executes a user-specified handler within a transaction.
+// Outermost transactions are re-run when Postgres reports a serialization failure or a deadlock,
+// so handlers must be safe to execute more than once. Nested calls run inside a SAVEPOINT
+// of the outer transaction and keep its isolation level.
 type TxManager interface {
+	// ReadCommitted runs the handler in a READ COMMITTED transaction
 	ReadCommitted(ctx context.Context, f Handler) error
+	// RepeatableRead runs the handler in a REPEATABLE READ transaction
+	RepeatableRead(ctx context.Context, f Handler) error
+	// Serializable runs the handler in a SERIALIZABLE transaction
+	Serializable(ctx context.Context, f Handler) error
+	// ReadOnly runs the handler in a READ ONLY transaction with a REPEATABLE READ snapshot
+	ReadOnly(ctx context.Context, f Handler) error
+	// SerializableReadOnly runs the handler in a SERIALIZABLE READ ONLY DEFERRABLE transaction
+	SerializableReadOnly(ctx context.Context, f Handler) error
 }
 
 // Transactor interface for working with transactions
@@ -28,10 +43,26 @@ type Client interface {
 	Close() error // Closes the database connection
 }
 
-// Query represents a database query with a name and raw SQL string
+// Query represents a database query with a name and raw SQL string.
+// QueryRaw uses positional $n placeholders, or :name parameters when the only argument
+// passed along with the query is a named.Binder (named.Args or named.Struct).
 type Query struct {
 	Name     string // Name of the query
 	QueryRaw string // Raw SQL query string
+
+	// Timeout limits the query duration: via SET LOCAL statement_timeout inside a transaction,
+	// via the context outside it. 0 leaves only the client-wide timeout.
+	Timeout time.Duration
+	// ReadOnly marks a query that does not modify data, it may be routed to a read replica
+	ReadOnly bool
+}
+
+// ErrQueryTimeout is matched by errors.Is when a query was canceled by a timeout
+var ErrQueryTimeout = errors.New("db: query timeout")
+
+// IsTimeout reports whether err is caused by a query timeout
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrQueryTimeout)
 }
 
 // SQLExecer combines both NamedExecer and QueryExecer interfaces
@@ -58,16 +89,51 @@ type QueryExecer interface {
 	QueryRowContext(ctx context.Context, q Query, args ...any) pgx.Row
 }
 
+// Batcher defines sending several queries in a single round trip
+type Batcher interface {
+	// SendBatch executes all queued queries and returns a result per query
+	// along with the first error encountered
+	SendBatch(ctx context.Context, b *Batch) ([]BatchResult, error)
+}
+
+// Copier defines bulk loading of rows via the COPY protocol
+type Copier interface {
+	// CopyFrom copies rows from src into the table and returns the number of copied rows
+	CopyFrom(ctx context.Context, q CopyQuery, src pgx.CopyFromSource) (int64, error)
+}
+
+// Acquirer defines taking a dedicated connection out of the pool,
+// e.g. for session-level state such as advisory locks or LISTEN
+type Acquirer interface {
+	// Acquire returns a connection that must be released by the caller
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// Notifier defines publishing and subscribing through Postgres LISTEN/NOTIFY
+type Notifier interface {
+	// Listen subscribes to channels on a dedicated connection and delivers notifications to handler
+	// until ctx is canceled, reconnecting and re-subscribing after failures.
+	// Notifications sent while the listener is reconnecting are not delivered.
+	Listen(ctx context.Context, handler NotificationHandler, channels ...string) error
+	// Notify sends payload to the channel, inside a transaction it is delivered on commit
+	Notify(ctx context.Context, channel, payload string) error
+}
+
 // Pinger defines a method to check database connectivity
 type Pinger interface {
 	// Ping checks if the database is reachable
 	Ping(ctx context.Context) error
 }
 
-// DB combines SQL execution, ping capability and close functionality
+// DB combines SQL execution, batching, bulk copy, transactions, dedicated connections,
+// LISTEN/NOTIFY, ping capability and close functionality
 type DB interface {
 	SQLExecer
+	Batcher
+	Copier
 	Transactor
+	Acquirer
+	Notifier
 	Pinger
 	Close() // Closes the database connection
 }
@@ -0,0 +1,294 @@
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/client/db/pg"
+	"github.com/WithSoull/platform_common/pkg/client/db/transaction"
+	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+)
+
+// DB is an in-memory fake of db.DB: calls are matched by db.Query.Name against registered expectations
+type DB struct {
+	mu            sync.Mutex
+	expectations  []*Expectation
+	unexpected    []string
+	txs           []*Tx
+	notifications []db.Notification
+
+	pingErr   error
+	beginErr  error
+	commitErr error
+	closed    bool
+}
+
+func NewDB() *DB {
+	return &DB{}
+}
+
+// Expect registers an expected call of the named query, by default expected once with any arguments
+func (d *DB) Expect(name string) *Expectation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e := &Expectation{name: name, anyArgs: true, times: 1}
+	d.expectations = append(d.expectations, e)
+	return e
+}
+
+// SetPingError makes Ping fail with err
+func (d *DB) SetPingError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pingErr = err
+}
+
+// SetBeginError makes BeginTx fail with err
+func (d *DB) SetBeginError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.beginErr = err
+}
+
+// SetCommitError makes Commit of subsequently started transactions fail with err
+func (d *DB) SetCommitError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.commitErr = err
+}
+
+// Transactions returns transactions started through BeginTx in start order
+func (d *DB) Transactions() []*Tx {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*Tx(nil), d.txs...)
+}
+
+// Notifications returns payloads sent through Notify
+func (d *DB) Notifications() []db.Notification {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]db.Notification(nil), d.notifications...)
+}
+
+// Closed reports whether Close was called
+func (d *DB) Closed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.closed
+}
+
+// ExpectationsWereMet returns an error describing unmet expectations and unexpected calls
+func (d *DB) ExpectationsWereMet() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var problems []string
+	for _, e := range d.expectations {
+		if e.unmet() {
+			problems = append(problems, "unmet expectation: "+e.String())
+		}
+	}
+	for _, call := range d.unexpected {
+		problems = append(problems, "unexpected call: "+call)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return errors.New("dbtest:\n" + strings.Join(problems, "\n"))
+}
+
+// call finds the expectation for the query and records the outcome
+func (d *DB) call(ctx context.Context, q db.Query, args []any) (*Expectation, error) {
+	inTx := false
+	if tx, ok := txctx.ExtractTx(ctx); ok {
+		if fake, ok := tx.(*Tx); ok && fake.closed() {
+			return nil, pgx.ErrTxClosed
+		}
+		inTx = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, e := range d.expectations {
+		if e.match(q.Name, args, inTx) {
+			e.calls++
+			return e, pg.WrapError(e.err)
+		}
+	}
+
+	call := fmt.Sprintf("%s with args %v (in_tx=%t)", q.Name, args, inTx)
+	d.unexpected = append(d.unexpected, call)
+	return nil, errors.Errorf("dbtest: unexpected call %s", call)
+}
+
+func (d *DB) ScanOneContext(ctx context.Context, dest any, q db.Query, args ...any) error {
+	r, err := d.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+
+	return pg.WrapError(pgxscan.ScanOne(dest, r))
+}
+
+func (d *DB) ScanAllContext(ctx context.Context, dest any, q db.Query, args ...any) error {
+	r, err := d.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+
+	return pg.WrapError(pgxscan.ScanAll(dest, r))
+}
+
+func (d *DB) ExecContext(ctx context.Context, q db.Query, args ...any) (pgconn.CommandTag, error) {
+	e, err := d.call(ctx, q, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.tag, nil
+}
+
+func (d *DB) QueryContext(ctx context.Context, q db.Query, args ...any) (pgx.Rows, error) {
+	e, err := d.call(ctx, q, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRows(e.columns, e.rows, nil), nil
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, q db.Query, args ...any) pgx.Row {
+	e, err := d.call(ctx, q, args)
+	if err != nil {
+		return row{rows: newRows(nil, nil, err)}
+	}
+
+	return row{rows: newRows(e.columns, e.rows, nil)}
+}
+
+func (d *DB) SendBatch(ctx context.Context, b *db.Batch) ([]db.BatchResult, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	results := make([]db.BatchResult, 0, b.Len())
+	var firstErr error
+	for _, item := range b.Items() {
+		res := db.BatchResult{Query: item.Query}
+		switch {
+		case item.Dest == nil:
+			res.CommandTag, res.Err = d.ExecContext(ctx, item.Query, item.Args...)
+		case item.One:
+			res.Err = d.ScanOneContext(ctx, item.Dest, item.Query, item.Args...)
+		default:
+			res.Err = d.ScanAllContext(ctx, item.Dest, item.Query, item.Args...)
+		}
+
+		if res.Err != nil && firstErr == nil {
+			firstErr = res.Err
+		}
+		results = append(results, res)
+	}
+
+	return results, firstErr
+}
+
+// CopyFrom is matched by CopyQuery.Name with the copied rows as arguments
+func (d *DB) CopyFrom(ctx context.Context, q db.CopyQuery, src pgx.CopyFromSource) (int64, error) {
+	var copied []any
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			return 0, err
+		}
+		copied = append(copied, values)
+	}
+	if err := src.Err(); err != nil {
+		return 0, err
+	}
+
+	if _, err := d.call(ctx, db.Query{Name: q.Name}, copied); err != nil {
+		return 0, err
+	}
+
+	return int64(len(copied)), nil
+}
+
+func (d *DB) BeginTx(_ context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.beginErr != nil {
+		return nil, d.beginErr
+	}
+
+	tx := &Tx{opts: txOptions, commitErr: d.commitErr}
+	d.txs = append(d.txs, tx)
+	return tx, nil
+}
+
+func (d *DB) Acquire(context.Context) (*pgxpool.Conn, error) {
+	return nil, ErrNotSupported
+}
+
+// Listen blocks until ctx is canceled, notifications are not simulated
+func (d *DB) Listen(ctx context.Context, _ db.NotificationHandler, _ ...string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (d *DB) Notify(_ context.Context, channel, payload string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.notifications = append(d.notifications, db.Notification{Channel: channel, Payload: payload})
+	return nil
+}
+
+func (d *DB) Ping(context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pingErr
+}
+
+func (d *DB) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+}
+
+type client struct {
+	db *DB
+}
+
+// NewClient wraps the fake into db.Client
+func NewClient(d *DB) db.Client {
+	return &client{db: d}
+}
+
+func (c *client) DB() db.DB {
+	return c.db
+}
+
+func (c *client) Close() error {
+	c.db.Close()
+	return nil
+}
+
+// NewTxManager returns the real transaction manager running on top of the fake
+func NewTxManager(d *DB, opts ...transaction.Option) db.TxManager {
+	return transaction.NewTransactionManager(d, opts...)
+}
@@ -0,0 +1,157 @@
+package dbtest
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgconn"
+)
+
+// ArgMatcher matches a single query argument
+type ArgMatcher interface {
+	Match(v any) bool
+	String() string
+}
+
+type anyArg struct{}
+
+func (anyArg) Match(any) bool { return true }
+func (anyArg) String() string { return "<any>" }
+
+// Any matches any argument value
+func Any() ArgMatcher {
+	return anyArg{}
+}
+
+type eqArg struct {
+	want any
+}
+
+func (e eqArg) Match(v any) bool { return reflect.DeepEqual(e.want, v) }
+func (e eqArg) String() string   { return fmt.Sprintf("%#v", e.want) }
+
+// Eq matches arguments deeply equal to want, plain values passed to WithArgs are wrapped in Eq
+func Eq(want any) ArgMatcher {
+	return eqArg{want: want}
+}
+
+type funcArg struct {
+	desc string
+	fn   func(v any) bool
+}
+
+func (f funcArg) Match(v any) bool { return f.fn(v) }
+func (f funcArg) String() string   { return f.desc }
+
+// MatchFunc matches arguments for which fn returns true, desc is used in failure messages
+func MatchFunc(desc string, fn func(v any) bool) ArgMatcher {
+	return funcArg{desc: desc, fn: fn}
+}
+
+// Expectation describes an expected call of a named query and its canned outcome
+type Expectation struct {
+	name    string
+	args    []ArgMatcher
+	anyArgs bool
+	inTx    *bool
+
+	columns []string
+	rows    [][]any
+	tag     pgconn.CommandTag
+	err     error
+
+	times int // expected number of calls, 0 means any number
+	calls int
+}
+
+// WithArgs sets matchers for the query arguments, plain values are compared with Eq
+func (e *Expectation) WithArgs(args ...any) *Expectation {
+	e.anyArgs = false
+	e.args = make([]ArgMatcher, 0, len(args))
+	for _, a := range args {
+		if m, ok := a.(ArgMatcher); ok {
+			e.args = append(e.args, m)
+			continue
+		}
+		e.args = append(e.args, Eq(a))
+	}
+
+	return e
+}
+
+// InTx expects the query to run inside (true) or outside (false) a transaction
+func (e *Expectation) InTx(inTx bool) *Expectation {
+	e.inTx = &inTx
+	return e
+}
+
+// ReturnRows sets the rows returned by the query
+func (e *Expectation) ReturnRows(columns []string, rows ...[]any) *Expectation {
+	e.columns = columns
+	e.rows = rows
+	return e
+}
+
+// ReturnResult sets the command tag returned by ExecContext, e.g. "INSERT 0 1"
+func (e *Expectation) ReturnResult(tag string) *Expectation {
+	e.tag = pgconn.CommandTag(tag)
+	return e
+}
+
+// ReturnError makes the query fail with err, driver errors are translated like the pg client does
+func (e *Expectation) ReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Times sets how many times the query is expected to be called
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// AnyTimes allows the query to be called any number of times, including zero
+func (e *Expectation) AnyTimes() *Expectation {
+	e.times = 0
+	return e
+}
+
+func (e *Expectation) exhausted() bool {
+	return e.times > 0 && e.calls >= e.times
+}
+
+func (e *Expectation) unmet() bool {
+	return e.times > 0 && e.calls < e.times
+}
+
+func (e *Expectation) match(name string, args []any, inTx bool) bool {
+	if e.name != name || e.exhausted() {
+		return false
+	}
+	if e.inTx != nil && *e.inTx != inTx {
+		return false
+	}
+	if e.anyArgs {
+		return true
+	}
+	if len(e.args) != len(args) {
+		return false
+	}
+
+	for i, m := range e.args {
+		if !m.Match(args[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *Expectation) String() string {
+	args := "<any>"
+	if !e.anyArgs {
+		args = fmt.Sprint(e.args)
+	}
+
+	return fmt.Sprintf("%s with args %s (called %d of %d times)", e.name, args, e.calls, e.times)
+}
@@ -0,0 +1,149 @@
+package dbtest
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/WithSoull/platform_common/pkg/client/db/pg"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
+)
+
+// rows is a pgx.Rows over canned values
+type rows struct {
+	columns []string
+	values  [][]any
+	pos     int
+	err     error
+	closed  bool
+}
+
+func newRows(columns []string, values [][]any, err error) *rows {
+	return &rows{columns: columns, values: values, pos: -1, err: err}
+}
+
+func (r *rows) Close() {
+	r.closed = true
+}
+
+func (r *rows) Err() error {
+	return r.err
+}
+
+func (r *rows) CommandTag() pgconn.CommandTag {
+	return pgconn.CommandTag(fmt.Sprintf("SELECT %d", len(r.values)))
+}
+
+func (r *rows) FieldDescriptions() []pgproto3.FieldDescription {
+	fields := make([]pgproto3.FieldDescription, 0, len(r.columns))
+	for _, c := range r.columns {
+		fields = append(fields, pgproto3.FieldDescription{Name: []byte(c)})
+	}
+
+	return fields
+}
+
+func (r *rows) Next() bool {
+	if r.closed || r.err != nil {
+		return false
+	}
+
+	r.pos++
+	if r.pos >= len(r.values) {
+		r.Close()
+		return false
+	}
+
+	return true
+}
+
+func (r *rows) Scan(dest ...any) error {
+	if r.pos < 0 || r.pos >= len(r.values) {
+		return fmt.Errorf("dbtest: scan called without a current row")
+	}
+
+	row := r.values[r.pos]
+	if len(dest) != len(row) {
+		return fmt.Errorf("dbtest: scan into %d destinations, row has %d values", len(dest), len(row))
+	}
+
+	for i := range dest {
+		if err := assign(dest[i], row[i]); err != nil {
+			return fmt.Errorf("dbtest: column %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *rows) Values() ([]any, error) {
+	if r.pos < 0 || r.pos >= len(r.values) {
+		return nil, fmt.Errorf("dbtest: values called without a current row")
+	}
+
+	return r.values[r.pos], nil
+}
+
+func (r *rows) RawValues() [][]byte {
+	return nil
+}
+
+// row is a pgx.Row over the first canned row
+type row struct {
+	rows *rows
+}
+
+func (r row) Scan(dest ...any) error {
+	defer r.rows.Close()
+
+	if r.rows.err != nil {
+		return r.rows.err
+	}
+	if !r.rows.Next() {
+		return pg.WrapError(pgx.ErrNoRows)
+	}
+
+	return r.rows.Scan(dest...)
+}
+
+// assign stores value into the pointer dest the way a driver would
+func assign(dest, value any) error {
+	if dest == nil {
+		return nil
+	}
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() {
+		return fmt.Errorf("destination %T is not a non-nil pointer", dest)
+	}
+	target := dv.Elem()
+
+	if value == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(target.Type()):
+		target.Set(v)
+	case target.Kind() == reflect.Pointer && v.Type().AssignableTo(target.Type().Elem()):
+		p := reflect.New(target.Type().Elem())
+		p.Elem().Set(v)
+		target.Set(p)
+	case target.Kind() == reflect.Interface && v.Type().Implements(target.Type()):
+		target.Set(v)
+	// Named types such as `type Status string` are converted, while int to string is a rune conversion a driver never does
+	case v.Type().ConvertibleTo(target.Type()) && (v.Kind() == reflect.String) == (target.Kind() == reflect.String):
+		target.Set(v.Convert(target.Type()))
+	default:
+		return fmt.Errorf("cannot assign %T to %s", value, target.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package dbtest
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+)
+
+// ErrNotSupported is returned by pgx features the fake does not simulate
+var ErrNotSupported = errors.New("dbtest: not supported")
+
+// Tx is a fake pgx.Tx recording how the transaction ended.
+// Queries made through db.DB inside the transaction are matched against the DB expectations.
+type Tx struct {
+	opts      pgx.TxOptions
+	commitErr error
+
+	mu         sync.Mutex
+	committed  bool
+	rolledBack bool
+	statements []string
+}
+
+// Options returns the options the transaction was started with
+func (t *Tx) Options() pgx.TxOptions {
+	return t.opts
+}
+
+// Committed reports whether the transaction was committed
+func (t *Tx) Committed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.committed
+}
+
+// RolledBack reports whether the transaction was rolled back
+func (t *Tx) RolledBack() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rolledBack
+}
+
+// Statements returns SQL executed directly on the transaction, e.g. SAVEPOINT commands
+func (t *Tx) Statements() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.statements...)
+}
+
+func (t *Tx) closed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.committed || t.rolledBack
+}
+
+func (t *Tx) Begin(context.Context) (pgx.Tx, error) {
+	return nil, ErrNotSupported
+}
+
+func (t *Tx) BeginFunc(context.Context, func(pgx.Tx) error) error {
+	return ErrNotSupported
+}
+
+func (t *Tx) Commit(context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.committed || t.rolledBack {
+		return pgx.ErrTxClosed
+	}
+	if t.commitErr != nil {
+		t.rolledBack = true
+		return t.commitErr
+	}
+
+	t.committed = true
+	return nil
+}
+
+func (t *Tx) Rollback(context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.committed || t.rolledBack {
+		return pgx.ErrTxClosed
+	}
+
+	t.rolledBack = true
+	return nil
+}
+
+func (t *Tx) CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error) {
+	return 0, ErrNotSupported
+}
+
+func (t *Tx) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
+	return nil
+}
+
+func (t *Tx) LargeObjects() pgx.LargeObjects {
+	return pgx.LargeObjects{}
+}
+
+func (t *Tx) Prepare(context.Context, string, string) (*pgconn.StatementDescription, error) {
+	return nil, ErrNotSupported
+}
+
+// Exec records the statement, it is used by the transaction manager for savepoints
+func (t *Tx) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.committed || t.rolledBack {
+		return nil, pgx.ErrTxClosed
+	}
+
+	t.statements = append(t.statements, sql)
+	command, _, _ := strings.Cut(sql, " ")
+	return pgconn.CommandTag(strings.ToUpper(command)), nil
+}
+
+func (t *Tx) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, ErrNotSupported
+}
+
+func (t *Tx) QueryRow(context.Context, string, ...any) pgx.Row {
+	return row{rows: newRows(nil, nil, ErrNotSupported)}
+}
+
+func (t *Tx) QueryFunc(context.Context, string, []any, []any, func(pgx.QueryFuncRow) error) (pgconn.CommandTag, error) {
+	return nil, ErrNotSupported
+}
+
+func (t *Tx) Conn() *pgx.Conn {
+	return nil
+}
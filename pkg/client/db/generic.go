@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"iter"
+
+	"github.com/georgysavva/scany/pgxscan"
+)
+
+// Get scans a single row into a value of type T, a struct or a single column type
+func Get[T any](ctx context.Context, e NamedExecer, q Query, args ...any) (T, error) {
+	var dest T
+	err := e.ScanOneContext(ctx, &dest, q, args...)
+	return dest, err
+}
+
+// Select scans all rows into a slice of T
+func Select[T any](ctx context.Context, e NamedExecer, q Query, args ...any) ([]T, error) {
+	var dest []T
+	err := e.ScanAllContext(ctx, &dest, q, args...)
+	return dest, err
+}
+
+// Iter streams rows one by one without loading the whole result set.
+// A query or scan error is yielded once and ends the iteration, rows are closed
+// when the loop is finished or left early.
+func Iter[T any](ctx context.Context, e QueryExecer, q Query, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		rows, err := e.QueryContext(ctx, q, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		scanner := pgxscan.NewRowScanner(rows)
+		for rows.Next() {
+			var v T
+			if err := scanner.Scan(&v); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
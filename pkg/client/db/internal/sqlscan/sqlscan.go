@@ -0,0 +1,138 @@
+package sqlscan
+
+import "strings"
+
+// Kind is the kind of a query token
+type Kind int
+
+const (
+	// Char is a single byte outside any of the tokens below
+	Char Kind = iota
+	// Quoted is a string literal, an E'...' string or a quoted identifier
+	Quoted
+	// DollarQuoted is a $tag$...$tag$ string
+	DollarQuoted
+	// LineComment is a -- comment up to, not including, the line break
+	LineComment
+	// BlockComment is a /* ... */ comment
+	BlockComment
+	// Positional is a $n placeholder
+	Positional
+)
+
+// Token is a part of a query starting at Pos.
+// Unterminated is set for quoted strings and comments running to the end of the query.
+type Token struct {
+	Kind         Kind
+	Text         string
+	Pos          int
+	Unterminated bool
+}
+
+// End returns the index after the token
+func (t Token) End() int {
+	return t.Pos + len(t.Text)
+}
+
+// Next returns the token starting at index i of query, i must be less than len(query)
+func Next(query string, i int) Token {
+	c := query[i]
+	switch {
+	case c == '\'' || c == '"':
+		end, ok := skipQuoted(query, i)
+		return token(query, Quoted, i, end, !ok)
+
+	case c == '-' && strings.HasPrefix(query[i:], "--"):
+		end := strings.IndexByte(query[i:], '\n')
+		if end < 0 {
+			return token(query, LineComment, i, len(query), false)
+		}
+		return token(query, LineComment, i, i+end, false)
+
+	case c == '/' && strings.HasPrefix(query[i:], "/*"):
+		end := strings.Index(query[i+2:], "*/")
+		if end < 0 {
+			return token(query, BlockComment, i, len(query), true)
+		}
+		return token(query, BlockComment, i, i+end+4, false)
+
+	case c == '$' && i+1 < len(query) && IsDigit(query[i+1]):
+		end := i + 1
+		for end < len(query) && IsDigit(query[end]) {
+			end++
+		}
+		return token(query, Positional, i, end, false)
+
+	case c == '$':
+		end, ok := skipDollarQuoted(query, i)
+		if end == i+1 {
+			return token(query, Char, i, end, false)
+		}
+		return token(query, DollarQuoted, i, end, !ok)
+	}
+
+	return token(query, Char, i, i+1, false)
+}
+
+func token(query string, kind Kind, start, end int, unterminated bool) Token {
+	return Token{Kind: kind, Text: query[start:end], Pos: start, Unterminated: unterminated}
+}
+
+// skipQuoted returns the index after the string literal or quoted identifier starting at i
+// and whether it is terminated
+func skipQuoted(query string, i int) (int, bool) {
+	quote := query[i]
+	// E'...' strings allow backslash escapes
+	escapes := quote == '\'' && i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') &&
+		(i == 1 || !IsIdentPart(query[i-2]))
+
+	for j := i + 1; j < len(query); j++ {
+		switch {
+		case escapes && query[j] == '\\':
+			j++
+		case query[j] == quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1, true
+		}
+	}
+
+	return len(query), false
+}
+
+// skipDollarQuoted returns the index after a $tag$...$tag$ string starting at i and whether it is terminated,
+// or i+1 when the dollar sign does not open one
+func skipDollarQuoted(query string, i int) (int, bool) {
+	j := i + 1
+	for j < len(query) && IsIdentPart(query[j]) && !(j == i+1 && IsDigit(query[j])) {
+		j++
+	}
+	if j >= len(query) || query[j] != '$' {
+		return i + 1, true
+	}
+
+	tag := query[i : j+1]
+	end := strings.Index(query[j+1:], tag)
+	if end < 0 {
+		return len(query), false
+	}
+
+	return j + 1 + end + len(tag), true
+}
+
+// IsDigit reports whether c is an ASCII digit
+func IsDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// IsIdentStart reports whether c may start an unquoted identifier
+func IsIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// IsIdentPart reports whether c may continue an unquoted identifier
+func IsIdentPart(c byte) bool {
+	return IsIdentStart(c) || IsDigit(c)
+}
@@ -0,0 +1,213 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/WithSoull/platform_common/pkg/closer"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRetryInterval = 5 * time.Second
+	defaultCheckInterval = 2 * time.Second
+	checkTimeout         = time.Second
+)
+
+type Logger interface {
+	Info(ctx context.Context, msg string, fields ...zap.Field)
+	Error(ctx context.Context, msg string, fields ...zap.Field)
+}
+
+// ElectorOption configures the leader elector
+type ElectorOption func(e *Elector)
+
+// WithRetryInterval sets how often a follower tries to take leadership
+func WithRetryInterval(d time.Duration) ElectorOption {
+	return func(e *Elector) {
+		e.retryInterval = d
+	}
+}
+
+// WithCheckInterval sets how often the leader verifies the connection holding the lock
+func WithCheckInterval(d time.Duration) ElectorOption {
+	return func(e *Elector) {
+		e.checkInterval = d
+	}
+}
+
+// OnElected sets the callback run in its own goroutine when leadership is taken.
+// Its context is canceled as soon as leadership is lost, the lock is released and OnDemoted
+// is run only after the callback returns, so it must return promptly once canceled.
+func OnElected(fn func(ctx context.Context)) ElectorOption {
+	return func(e *Elector) {
+		e.onElected = fn
+	}
+}
+
+// OnDemoted sets the callback run after leadership is lost or released
+func OnDemoted(fn func(ctx context.Context)) ElectorOption {
+	return func(e *Elector) {
+		e.onDemoted = fn
+	}
+}
+
+// Elector keeps at most one replica of a service as leader by holding a session-level advisory lock
+type Elector struct {
+	locker *Locker
+	name   string
+	key    int64
+	logger Logger
+
+	retryInterval time.Duration
+	checkInterval time.Duration
+	onElected     func(ctx context.Context)
+	onDemoted     func(ctx context.Context)
+
+	leader    atomic.Bool
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	startOnce sync.Once
+}
+
+// NewElector creates an elector competing for the lock derived from name
+func NewElector(locker *Locker, name string, logger Logger, opts ...ElectorOption) *Elector {
+	e := &Elector{
+		locker:        locker,
+		name:          name,
+		key:           Key(name),
+		logger:        logger,
+		retryInterval: defaultRetryInterval,
+		checkInterval: defaultCheckInterval,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// IsLeader reports whether this replica currently holds leadership
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Start campaigns for leadership in the background and registers the elector in the global closer
+func (e *Elector) Start(ctx context.Context) {
+	e.startOnce.Do(func() {
+		ctx, e.cancel = context.WithCancel(ctx)
+
+		e.wg.Add(1)
+		go e.run(ctx)
+
+		closer.AddNamed("leader election "+e.name, e.Close)
+	})
+}
+
+// Close gives up leadership and stops campaigning
+func (e *Elector) Close(ctx context.Context) error {
+	if e.cancel == nil {
+		return nil
+	}
+	e.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Elector) run(ctx context.Context) {
+	defer e.wg.Done()
+
+	for {
+		lock, ok, err := e.locker.TryAcquire(ctx, e.key)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			e.logger.Error(ctx, "leader election attempt failed", zap.String("name", e.name), zap.Error(err))
+		case ok:
+			e.lead(ctx, lock)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.retryInterval):
+		}
+	}
+}
+
+// lead holds leadership until the lock connection is lost or ctx is canceled
+func (e *Elector) lead(ctx context.Context, lock *Lock) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	e.leader.Store(true)
+	e.logger.Info(ctx, "leadership acquired", zap.String("name", e.name))
+
+	elected := make(chan struct{})
+	if e.onElected != nil {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			defer close(elected)
+			e.onElected(leaderCtx)
+		}()
+	} else {
+		close(elected)
+	}
+
+	ticker := time.NewTicker(e.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.demote(ctx, cancel, elected, lock, nil)
+			return
+		case <-ticker.C:
+			checkCtx, checkCancel := context.WithTimeout(ctx, checkTimeout)
+			err := lock.Alive(checkCtx)
+			checkCancel()
+
+			if err != nil && ctx.Err() == nil {
+				e.demote(ctx, cancel, elected, lock, err)
+				return
+			}
+		}
+	}
+}
+
+// demote stops the OnElected callback and waits for it before releasing the lock,
+// so that its work does not overlap with the next leader
+func (e *Elector) demote(ctx context.Context, cancel context.CancelFunc, elected <-chan struct{}, lock *Lock, cause error) {
+	cancel()
+	e.leader.Store(false)
+	<-elected
+
+	releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), checkTimeout)
+	defer releaseCancel()
+
+	if err := lock.Release(releaseCtx); err != nil {
+		e.logger.Error(ctx, "leadership lock release failed", zap.String("name", e.name), zap.Error(err))
+	}
+
+	if cause != nil {
+		e.logger.Error(ctx, "leadership lost", zap.String("name", e.name), zap.Error(cause))
+	} else {
+		e.logger.Info(ctx, "leadership released", zap.String("name", e.name))
+	}
+
+	if e.onDemoted != nil {
+		e.onDemoted(context.WithoutCancel(ctx))
+	}
+}
@@ -0,0 +1,146 @@
+package lock
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+)
+
+// ErrNoTransaction is returned when a transaction-level lock is requested outside a transaction
+var ErrNoTransaction = errors.New("lock: transaction-level lock requires a transaction")
+
+var (
+	xactLockQuery    = db.Query{Name: "lock.xact_lock", QueryRaw: `SELECT pg_advisory_xact_lock($1)`}
+	tryXactLockQuery = db.Query{Name: "lock.try_xact_lock", QueryRaw: `SELECT pg_try_advisory_xact_lock($1)`}
+)
+
+// Key derives an advisory lock key from a human-readable name
+func Key(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// Locker acquires Postgres advisory locks
+type Locker struct {
+	db db.DB
+}
+
+func NewLocker(dbc db.DB) *Locker {
+	return &Locker{
+		db: dbc,
+	}
+}
+
+// Acquire blocks until the session-level lock is taken on a dedicated connection
+func (l *Locker) Acquire(ctx context.Context, key int64) (*Lock, error) {
+	conn, err := l.db.Acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "lock: acquire connection")
+	}
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, key); err != nil {
+		conn.Release()
+		return nil, errors.Wrapf(err, "lock: acquire %d", key)
+	}
+
+	return &Lock{key: key, conn: conn}, nil
+}
+
+// TryAcquire takes the session-level lock if it is free and reports whether it was taken
+func (l *Locker) TryAcquire(ctx context.Context, key int64) (*Lock, bool, error) {
+	conn, err := l.db.Acquire(ctx)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "lock: acquire connection")
+	}
+
+	var ok bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, false, errors.Wrapf(err, "lock: try acquire %d", key)
+	}
+
+	if !ok {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	return &Lock{key: key, conn: conn}, true, nil
+}
+
+// AcquireTx blocks until the transaction-level lock is taken, it is released on commit or rollback
+func (l *Locker) AcquireTx(ctx context.Context, key int64) error {
+	if _, ok := txctx.ExtractTx(ctx); !ok {
+		return ErrNoTransaction
+	}
+
+	_, err := l.db.ExecContext(ctx, xactLockQuery, key)
+	return errors.Wrapf(err, "lock: acquire tx %d", key)
+}
+
+// TryAcquireTx takes the transaction-level lock if it is free and reports whether it was taken
+func (l *Locker) TryAcquireTx(ctx context.Context, key int64) (bool, error) {
+	if _, ok := txctx.ExtractTx(ctx); !ok {
+		return false, ErrNoTransaction
+	}
+
+	var ok bool
+	if err := l.db.QueryRowContext(ctx, tryXactLockQuery, key).Scan(&ok); err != nil {
+		return false, errors.Wrapf(err, "lock: try acquire tx %d", key)
+	}
+
+	return ok, nil
+}
+
+// Lock is a session-level advisory lock held on a dedicated connection
+type Lock struct {
+	key int64
+
+	mu   sync.Mutex
+	conn *pgxpool.Conn
+}
+
+// Key returns the lock key
+func (l *Lock) Key() int64 {
+	return l.key
+}
+
+// Alive checks that the connection holding the lock is still open
+func (l *Lock) Alive(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return errors.New("lock: released")
+	}
+
+	_, err := l.conn.Exec(ctx, `SELECT 1`)
+	return err
+}
+
+// Release unlocks and returns the connection to the pool.
+// If unlocking fails the connection is closed, which releases the lock on the server.
+func (l *Lock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+	conn := l.conn
+	l.conn = nil
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+		_ = conn.Conn().Close(context.WithoutCancel(ctx))
+		conn.Release()
+		return errors.Wrapf(err, "lock: release %d", l.key)
+	}
+
+	conn.Release()
+	return nil
+}
@@ -0,0 +1,372 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// DefaultTable is the table where applied migrations are recorded
+const DefaultTable = "schema_migrations"
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+type Logger interface {
+	Info(ctx context.Context, msg string, fields ...zap.Field)
+}
+
+// Migration is a versioned pair of up/down SQL scripts
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up script
+}
+
+type applied struct {
+	Version  int64  `db:"version"`
+	Name     string `db:"name"`
+	Checksum string `db:"checksum"`
+}
+
+// Option configures the migrator
+type Option func(m *Migrator)
+
+// WithTable sets the table where applied migrations are recorded
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithLockKey sets the advisory lock key, by default it is derived from the table name
+func WithLockKey(key int64) Option {
+	return func(m *Migrator) {
+		m.lockKey = &key
+	}
+}
+
+// WithTimeout bounds a whole Up, Down or Pending run including the wait for the migration lock,
+// by default only ctx bounds it and the client-wide query timeout does not apply
+func WithTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.timeout = timeout
+	}
+}
+
+// WithDryRun makes the migrator print pending SQL to w instead of applying it
+func WithDryRun(w io.Writer) Option {
+	return func(m *Migrator) {
+		m.dryRun = w
+	}
+}
+
+// Migrator applies migrations from a file system, files are named <version>_<name>.up.sql and <version>_<name>.down.sql
+type Migrator struct {
+	db     db.DB
+	fsys   fs.FS
+	logger Logger
+
+	table   string
+	lockKey *int64
+	timeout time.Duration
+	dryRun  io.Writer
+}
+
+func NewMigrator(dbc db.DB, fsys fs.FS, logger Logger, opts ...Option) *Migrator {
+	m := &Migrator{
+		db:     dbc,
+		fsys:   fsys,
+		logger: logger,
+		table:  DefaultTable,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Up applies all pending migrations in a single transaction
+func (m *Migrator) Up(ctx context.Context) error {
+	migrations, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	return m.locked(ctx, migrations, func(ctx context.Context, tx pgx.Tx, done map[int64]applied) error {
+		for _, mig := range migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+
+			if err := m.apply(ctx, tx, mig, mig.Up, "up"); err != nil {
+				return err
+			}
+
+			if m.dryRun != nil {
+				continue
+			}
+			if _, err := tx.Exec(ctx, m.query(`INSERT INTO %s (version, name, checksum) VALUES ($1, $2, $3)`),
+				mig.Version, mig.Name, mig.Checksum); err != nil {
+				return errors.Wrapf(err, "record migration %d", mig.Version)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down rolls back the last steps applied migrations in a single transaction
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return errors.Errorf("invalid down steps %d, must be positive", steps)
+	}
+
+	migrations, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, mig := range migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	return m.locked(ctx, migrations, func(ctx context.Context, tx pgx.Tx, done map[int64]applied) error {
+		versions := make([]int64, 0, len(done))
+		for v := range done {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, v := range versions[:min(steps, len(versions))] {
+			mig, ok := byVersion[v]
+			if !ok || mig.Down == "" {
+				return errors.Errorf("no down migration for version %d", v)
+			}
+
+			if err := m.apply(ctx, tx, mig, mig.Down, "down"); err != nil {
+				return err
+			}
+
+			if m.dryRun != nil {
+				continue
+			}
+			if _, err := tx.Exec(ctx, m.query(`DELETE FROM %s WHERE version = $1`), v); err != nil {
+				return errors.Wrapf(err, "forget migration %d", v)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Pending returns migrations that have not been applied yet
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	err = m.locked(ctx, migrations, func(ctx context.Context, _ pgx.Tx, done map[int64]applied) error {
+		for _, mig := range migrations {
+			if _, ok := done[mig.Version]; !ok {
+				pending = append(pending, mig)
+			}
+		}
+		return nil
+	})
+
+	return pending, err
+}
+
+// locked runs fn in a transaction holding the migration advisory lock, so that only one replica migrates at a time.
+// The transaction runs on a dedicated connection: waiting for the lock and long DDL
+// are not bounded by the client-wide query timeout.
+func (m *Migrator) locked(ctx context.Context, migrations []Migration, fn func(ctx context.Context, tx pgx.Tx, done map[int64]applied) error) error {
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquire migration connection")
+	}
+	defer conn.Release()
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return errors.Wrap(err, "begin migration transaction")
+	}
+	// Rollback after a successful commit is a no-op
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, m.key()); err != nil {
+		return errors.Wrap(err, "acquire migration lock")
+	}
+
+	if _, err := tx.Exec(ctx, m.query(`CREATE TABLE IF NOT EXISTS %s (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT        NOT NULL,
+		checksum   TEXT        NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)); err != nil {
+		return errors.Wrap(err, "create migrations table")
+	}
+
+	var rows []applied
+	if err := pgxscan.Select(ctx, tx, &rows, m.query(`SELECT version, name, checksum FROM %s ORDER BY version`)); err != nil {
+		return errors.Wrap(err, "load applied migrations")
+	}
+
+	done := make(map[int64]applied, len(rows))
+	for _, r := range rows {
+		done[r.Version] = r
+	}
+
+	if err := verify(migrations, done); err != nil {
+		return err
+	}
+
+	if err := fn(ctx, tx, done); err != nil {
+		return err
+	}
+
+	// Dry runs only print pending SQL, the deferred rollback discards the migrations table if it was just created
+	if m.dryRun != nil {
+		return nil
+	}
+
+	return errors.Wrap(tx.Commit(ctx), "commit migrations")
+}
+
+func (m *Migrator) apply(ctx context.Context, tx pgx.Tx, mig Migration, sql, direction string) error {
+	if m.dryRun != nil {
+		_, err := fmt.Fprintf(m.dryRun, "-- %d_%s (%s)\n%s\n\n", mig.Version, mig.Name, direction, strings.TrimSpace(sql))
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return errors.Wrapf(err, "migration %d_%s %s", mig.Version, mig.Name, direction)
+	}
+
+	m.logger.Info(ctx, "migration applied",
+		zap.Int64("version", mig.Version),
+		zap.String("name", mig.Name),
+		zap.String("direction", direction),
+	)
+
+	return nil
+}
+
+// verify fails when an applied migration was modified after it had been applied
+func verify(migrations []Migration, done map[int64]applied) error {
+	for _, mig := range migrations {
+		a, ok := done[mig.Version]
+		if ok && a.Checksum != mig.Checksum {
+			return errors.Errorf("checksum mismatch for applied migration %d_%s", mig.Version, mig.Name)
+		}
+	}
+
+	return nil
+}
+
+// load reads and sorts migrations from the file system
+func (m *Migrator) load() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "read migrations")
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		file := entry.Name()
+		var base, suffix string
+		switch {
+		case strings.HasSuffix(file, upSuffix):
+			base, suffix = strings.TrimSuffix(file, upSuffix), upSuffix
+		case strings.HasSuffix(file, downSuffix):
+			base, suffix = strings.TrimSuffix(file, downSuffix), downSuffix
+		default:
+			continue
+		}
+
+		rawVersion, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(rawVersion, 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid migration version in %s", file)
+		}
+
+		content, err := fs.ReadFile(m.fsys, path.Clean(file))
+		if err != nil {
+			return nil, errors.Wrapf(err, "read migration %s", file)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: name}
+			byVersion[version] = mig
+		}
+
+		if suffix == upSuffix {
+			sum := sha256.Sum256(content)
+			mig.Up = string(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, errors.Errorf("no up migration for version %d", mig.Version)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func (m *Migrator) query(format string) string {
+	return fmt.Sprintf(format, pgx.Identifier(strings.Split(m.table, ".")).Sanitize())
+}
+
+func (m *Migrator) key() int64 {
+	if m.lockKey != nil {
+		return *m.lockKey
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("migrate:" + m.table))
+	return int64(h.Sum64())
+}
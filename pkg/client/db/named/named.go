@@ -0,0 +1,225 @@
+package named
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/client/db/internal/sqlscan"
+	"github.com/pkg/errors"
+)
+
+// Binder resolves values of :name parameters
+type Binder interface {
+	Lookup(name string) (any, bool)
+}
+
+// Args binds :name parameters from a map
+type Args map[string]any
+
+func (a Args) Lookup(name string) (any, bool) {
+	v, ok := a[name]
+	return v, ok
+}
+
+// structArgs binds :name parameters from exported struct fields
+type structArgs map[string]any
+
+func (s structArgs) Lookup(name string) (any, bool) {
+	v, ok := s[name]
+	return v, ok
+}
+
+// Struct binds :name parameters from the fields of a struct or a pointer to a struct.
+// A field is bound by its `db` tag or, without a tag, by its name in snake_case; `db:"-"` skips it.
+func Struct(v any) Binder {
+	args := make(structArgs)
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return args
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return args
+	}
+
+	collectFields(rv, args)
+	return args
+}
+
+func collectFields(rv reflect.Value, args structArgs) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+
+		// Embedded structs without a tag contribute their own fields, as in scany
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			collectFields(rv.Field(i), args)
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = toSnakeCase(field.Name)
+		}
+		args[name] = rv.Field(i).Interface()
+	}
+}
+
+// Compiled is a named query rewritten to positional $n placeholders
+type Compiled struct {
+	SQL   string   // Query with positional placeholders
+	Names []string // Parameter name for each placeholder, $1 is Names[0]
+}
+
+// Args returns positional arguments for the compiled query
+func (c *Compiled) Args(b Binder) ([]any, error) {
+	args := make([]any, 0, len(c.Names))
+	for _, name := range c.Names {
+		v, ok := b.Lookup(name)
+		if !ok {
+			return nil, errors.Errorf("named: missing value for parameter :%s", name)
+		}
+		args = append(args, v)
+	}
+
+	return args, nil
+}
+
+type cacheEntry struct {
+	raw      string
+	compiled *Compiled
+}
+
+var cache sync.Map // Query.Name -> *cacheEntry
+
+// Bind compiles q into positional form and resolves its arguments.
+// Compilation is cached per Query.Name, queries without a name are compiled on each call.
+func Bind(q db.Query, b Binder) (db.Query, []any, error) {
+	compiled, err := compileCached(q)
+	if err != nil {
+		return q, nil, err
+	}
+
+	args, err := compiled.Args(b)
+	if err != nil {
+		return q, nil, errors.Wrapf(err, "query %s", q.Name)
+	}
+
+	q.QueryRaw = compiled.SQL
+	return q, args, nil
+}
+
+func compileCached(q db.Query) (*Compiled, error) {
+	if q.Name == "" {
+		return Compile(q.QueryRaw)
+	}
+
+	if e, ok := cache.Load(q.Name); ok && e.(*cacheEntry).raw == q.QueryRaw {
+		return e.(*cacheEntry).compiled, nil
+	}
+
+	compiled, err := Compile(q.QueryRaw)
+	if err != nil {
+		return nil, err
+	}
+	cache.Store(q.Name, &cacheEntry{raw: q.QueryRaw, compiled: compiled})
+
+	return compiled, nil
+}
+
+// Compile rewrites :name parameters into $n placeholders, a query mixing them with $n placeholders is rejected.
+// String literals, quoted identifiers, dollar-quoted strings, comments and :: casts are left untouched.
+func Compile(query string) (*Compiled, error) {
+	var (
+		sb        strings.Builder
+		names     []string
+		positions = make(map[string]int)
+	)
+	sb.Grow(len(query))
+
+	for i := 0; i < len(query); {
+		tok := sqlscan.Next(query, i)
+		if tok.Unterminated {
+			return nil, unterminatedError(tok)
+		}
+
+		c := query[i]
+		switch {
+		// Positional placeholders would collide with the numbers assigned to :name parameters
+		case tok.Kind == sqlscan.Positional:
+			return nil, errors.Errorf("named: positional placeholder %s at offset %d in a named query", tok.Text, tok.Pos)
+
+		case tok.Kind != sqlscan.Char:
+			sb.WriteString(tok.Text)
+			i = tok.End()
+
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			sb.WriteString("::")
+			i += 2
+
+		case c == ':' && i+1 < len(query) && sqlscan.IsIdentStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && sqlscan.IsIdentPart(query[end]) {
+				end++
+			}
+
+			name := query[i+1 : end]
+			pos, ok := positions[name]
+			if !ok {
+				names = append(names, name)
+				pos = len(names)
+				positions[name] = pos
+			}
+			sb.WriteString("$" + strconv.Itoa(pos))
+			i = end
+
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+
+	return &Compiled{SQL: sb.String(), Names: names}, nil
+}
+
+func unterminatedError(tok sqlscan.Token) error {
+	switch tok.Kind {
+	case sqlscan.BlockComment:
+		return errors.New("named: unterminated comment")
+	case sqlscan.DollarQuoted:
+		return errors.Errorf("named: unterminated dollar-quoted string at offset %d", tok.Pos)
+	default:
+		return errors.Errorf("named: unterminated quoted literal at offset %d", tok.Pos)
+	}
+}
+
+func toSnakeCase(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
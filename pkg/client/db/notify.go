@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+)
+
+// Notification is a message delivered by Postgres NOTIFY
+type Notification struct {
+	PID     uint32 // Backend pid of the notifying session
+	Channel string // Channel the notification was sent to
+	Payload string // Notification payload
+}
+
+// NotificationHandler processes a received notification
+type NotificationHandler func(ctx context.Context, n Notification)
+
+// ChanHandler returns a handler forwarding notifications into ch until ctx is canceled
+func ChanHandler(ch chan<- Notification) NotificationHandler {
+	return func(ctx context.Context, n Notification) {
+		select {
+		case ch <- n:
+		case <-ctx.Done():
+		}
+	}
+}
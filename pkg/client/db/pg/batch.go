@@ -0,0 +1,98 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+)
+
+func (p *pg) SendBatch(ctx context.Context, b *db.Batch) ([]db.BatchResult, error) {
+	if b == nil || b.Len() == 0 {
+		return nil, nil
+	}
+
+	items := make([]db.BatchItem, 0, b.Len())
+	for _, item := range b.Items() {
+		q, args, err := bindNamed(item.Query, item.Args)
+		if err != nil {
+			return nil, err
+		}
+		item.Query, item.Args = q, args
+		items = append(items, item)
+	}
+
+	ctx, finish, err := p.queryScope(ctx, db.Query{Timeout: batchTimeout(items)})
+	if err != nil {
+		return nil, err
+	}
+	defer finish()
+
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		p.logQuery(ctx, item.Query, item.Args...)
+		batch.Queue(item.Query.QueryRaw, item.Args...)
+	}
+
+	var br pgx.BatchResults
+	tx, ok := txctx.ExtractTx(ctx)
+	if ok {
+		br = tx.SendBatch(ctx, batch)
+	} else {
+		br = p.dbc.SendBatch(ctx, batch)
+	}
+
+	results := make([]db.BatchResult, 0, len(items))
+	var firstErr error
+	for _, item := range items {
+		_, done := p.startQuery(ctx, item.Query, item.Args...)
+
+		res := db.BatchResult{Query: item.Query}
+		if item.Dest != nil {
+			res.Err = scanBatchResult(br, item)
+		} else {
+			res.CommandTag, res.Err = br.Exec()
+		}
+		res.Err = WrapError(res.Err)
+		done(res.Err)
+
+		if res.Err != nil && firstErr == nil {
+			firstErr = res.Err
+		}
+		results = append(results, res)
+	}
+
+	if err := br.Close(); err != nil && firstErr == nil {
+		firstErr = WrapError(err)
+	}
+
+	return results, firstErr
+}
+
+func scanBatchResult(br pgx.BatchResults, item db.BatchItem) error {
+	rows, err := br.Query()
+	if err != nil {
+		return err
+	}
+
+	if item.One {
+		return pgxscan.ScanOne(item.Dest, rows)
+	}
+
+	return pgxscan.ScanAll(item.Dest, rows)
+}
+
+// batchTimeout returns the smallest Query.Timeout of items, 0 when none is set
+func batchTimeout(items []db.BatchItem) time.Duration {
+	var timeout time.Duration
+	for _, item := range items {
+		if t := item.Query.Timeout; t > 0 && (timeout == 0 || t < timeout) {
+			timeout = t
+		}
+	}
+
+	return timeout
+}
@@ -2,6 +2,7 @@ package pg
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/WithSoull/platform_common/pkg/client/db"
@@ -11,9 +12,13 @@ import (
 )
 
 type pgClient struct {
-	masterDBC db.DB
+	masterDBC  db.DB
+	unregister []func()
 }
 
+// PGConfig is the required client configuration.
+// Optional settings are read when the config also implements PoolConfig, ConnectRetryConfig,
+// SlowQueryConfig or ReplicaConfig, otherwise their zero-value defaults apply.
 type PGConfig interface {
 	DSN() string
 	Timeout() time.Duration
@@ -21,21 +26,72 @@ type PGConfig interface {
 }
 
 func NewPGClient(ctx context.Context, logger Logger, cfg PGConfig) (db.Client, error) {
-	dbc, err := pgxpool.Connect(ctx, cfg.DSN())
+	primaryCfg, err := poolConfig(cfg.DSN(), cfg)
+	if err != nil {
+		return nil, errors.Errorf("failed to parse db dsn: %v", err.Error())
+	}
+
+	dbc, err := connect(ctx, logger, cfg, primaryCfg)
 	if err != nil {
 		return nil, errors.Errorf("failed to connect to db: %v", err.Error())
 	}
 
+	masterDBC := NewDB(dbc, logger, cfg)
+	unregister := []func(){registerPoolMetrics(ctx, logger, "primary", dbc)}
+	replicaCfg, ok := cfg.(ReplicaConfig)
+	if !ok || len(replicaCfg.ReplicaDSNs()) == 0 {
+		return &pgClient{
+			masterDBC:  masterDBC,
+			unregister: unregister,
+		}, nil
+	}
+
+	replicas := make([]*pgxpool.Pool, 0, len(replicaCfg.ReplicaDSNs()))
+	for i, dsn := range replicaCfg.ReplicaDSNs() {
+		poolCfg, err := poolConfig(dsn, cfg)
+		if err != nil {
+			unregisterAll(unregister)
+			closePools(dbc, replicas)
+			return nil, errors.Errorf("failed to parse replica %d dsn: %v", i, err.Error())
+		}
+		// Unreachable replicas are kept out of rotation by the health check instead of failing startup
+		poolCfg.LazyConnect = true
+
+		replica, err := pgxpool.ConnectConfig(ctx, poolCfg)
+		if err != nil {
+			unregisterAll(unregister)
+			closePools(dbc, replicas)
+			return nil, errors.Errorf("failed to connect to replica %d: %v", i, err.Error())
+		}
+		replicas = append(replicas, replica)
+		unregister = append(unregister, registerPoolMetrics(ctx, logger, fmt.Sprintf("replica_%d", i), replica))
+	}
+
 	return &pgClient{
-		masterDBC: NewDB(dbc, logger, cfg),
+		masterDBC:  newRoutedDB(ctx, masterDBC, replicas, logger, cfg, replicaCfg),
+		unregister: unregister,
 	}, nil
 }
 
+func unregisterAll(unregister []func()) {
+	for _, fn := range unregister {
+		fn()
+	}
+}
+
+func closePools(primary *pgxpool.Pool, replicas []*pgxpool.Pool) {
+	for _, replica := range replicas {
+		replica.Close()
+	}
+	primary.Close()
+}
+
 func (c *pgClient) DB() db.DB {
 	return c.masterDBC
 }
 
 func (c *pgClient) Close() error {
+	unregisterAll(c.unregister)
 	if c.masterDBC != nil {
 		c.masterDBC.Close()
 	}
@@ -0,0 +1,64 @@
+package pg
+
+import "time"
+
+// PoolConfig is an optional PGConfig extension with pgxpool settings, zero values keep pgxpool defaults
+type PoolConfig interface {
+	// MaxConns returns the maximum pool size
+	MaxConns() int32
+	// MinConns returns the number of connections kept open when idle
+	MinConns() int32
+	// MaxConnLifetime returns the age after which a connection is closed
+	MaxConnLifetime() time.Duration
+	// MaxConnIdleTime returns the idle time after which a connection is closed
+	MaxConnIdleTime() time.Duration
+	// HealthCheckPeriod returns how often idle connections are checked
+	HealthCheckPeriod() time.Duration
+}
+
+// ReplicaConfig is an optional PGConfig extension, without it reads stay on the primary
+type ReplicaConfig interface {
+	// ReplicaDSNs returns read replica DSNs, reads stay on the primary when empty
+	ReplicaDSNs() []string
+	// ReplicaBalancer returns BalancerRoundRobin or BalancerLeastConnection
+	ReplicaBalancer() string
+	// ReplicaMaxLag returns the replication lag after which a replica leaves rotation, 0 disables the check
+	ReplicaMaxLag() time.Duration
+	// ReplicaCheckInterval returns how often replica health is checked
+	ReplicaCheckInterval() time.Duration
+}
+
+// ConnectRetryConfig is an optional PGConfig extension, without it the primary is connected once
+type ConnectRetryConfig interface {
+	// ConnectMaxWait returns how long startup keeps retrying to connect and ping the primary, 0 tries once
+	ConnectMaxWait() time.Duration
+}
+
+// SlowQueryConfig is an optional PGConfig extension, without it slow queries are not logged
+type SlowQueryConfig interface {
+	// SlowQueryThreshold returns the duration after which a query is logged at warn level, 0 disables it
+	SlowQueryThreshold() time.Duration
+	// SlowQueryExplainRate returns the share (0..1) of slow queries whose EXPLAIN plan is attached to the log
+	SlowQueryExplainRate() float64
+}
+
+func connectMaxWait(cfg PGConfig) time.Duration {
+	if c, ok := cfg.(ConnectRetryConfig); ok {
+		return c.ConnectMaxWait()
+	}
+	return 0
+}
+
+func slowQueryThreshold(cfg PGConfig) time.Duration {
+	if c, ok := cfg.(SlowQueryConfig); ok {
+		return c.SlowQueryThreshold()
+	}
+	return 0
+}
+
+func slowQueryExplainRate(cfg PGConfig) float64 {
+	if c, ok := cfg.(SlowQueryConfig); ok {
+		return c.SlowQueryExplainRate()
+	}
+	return 0
+}
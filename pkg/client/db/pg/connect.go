@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	connectInitialBackoff = 100 * time.Millisecond
+	connectMaxBackoff     = 5 * time.Second
+)
+
+// connect opens the pool and pings it, retrying with exponential backoff for up to ConnectMaxWait
+func connect(ctx context.Context, logger Logger, cfg PGConfig, poolCfg *pgxpool.Config) (*pgxpool.Pool, error) {
+	deadline := time.Now().Add(connectMaxWait(cfg))
+	backoff := connectInitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		logger.Debug(ctx, "PG connecting", zap.Int("attempt", attempt))
+
+		pool, err := connectOnce(ctx, poolCfg)
+		if err == nil {
+			return pool, nil
+		}
+		if ctx.Err() != nil {
+			return nil, errors.Wrapf(ctx.Err(), "connect attempt %d: %v", attempt, err)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, errors.Wrapf(err, "connect attempt %d", attempt)
+		}
+
+		wait := min(backoff, remaining)
+		logger.Warn(ctx, "PG connection failed, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", wait),
+			zap.Duration("remaining", remaining),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Wrapf(ctx.Err(), "connect attempt %d: %v", attempt, err)
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, connectMaxBackoff)
+	}
+}
+
+func connectOnce(ctx context.Context, poolCfg *pgxpool.Config) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.ConnectConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, errors.Wrap(err, "ping")
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,48 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
+	"github.com/jackc/pgx/v4"
+)
+
+// CopyFrom is bounded only by ctx: the operation timeout is meant for single queries, not bulk loads
+func (p *pg) CopyFrom(ctx context.Context, q db.CopyQuery, src pgx.CopyFromSource) (int64, error) {
+	query := copyQuery(q)
+	p.logQuery(ctx, query)
+
+	ctx, done := p.startCopyQuery(ctx, query)
+
+	var (
+		n   int64
+		err error
+	)
+	tx, ok := txctx.ExtractTx(ctx)
+	if ok {
+		n, err = tx.CopyFrom(ctx, q.Table, q.Columns, src)
+	} else {
+		n, err = p.dbc.CopyFrom(ctx, q.Table, q.Columns, src)
+	}
+
+	err = WrapError(err)
+	done(err)
+
+	return n, err
+}
+
+// copyQuery renders the COPY statement used for logging and tracing
+func copyQuery(q db.CopyQuery) db.Query {
+	columns := make([]string, 0, len(q.Columns))
+	for _, c := range q.Columns {
+		columns = append(columns, pgx.Identifier{c}.Sanitize())
+	}
+
+	return db.Query{
+		Name:     q.Name,
+		QueryRaw: fmt.Sprintf("COPY %s (%s) FROM STDIN BINARY", q.Table.Sanitize(), strings.Join(columns, ", ")),
+	}
+}
@@ -0,0 +1,109 @@
+package pg
+
+import (
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/sys"
+	"github.com/WithSoull/platform_common/pkg/sys/codes"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+)
+
+// SQLSTATE codes translated into common errors
+const (
+	sqlStateUniqueViolation      = "23505"
+	sqlStateForeignKeyViolation  = "23503"
+	sqlStateCheckViolation       = "23514"
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+	sqlStateQueryCanceled        = "57014"
+)
+
+// Error is a database error translated into sys.CommonError.
+// Both the common error and the original driver error are reachable through errors.As and errors.Is.
+type Error struct {
+	common  *sys.CommonError
+	cause   error
+	timeout bool
+
+	SQLState   string // SQLSTATE code, empty for non-server errors
+	Constraint string // Violated constraint name
+	Table      string // Table the error relates to
+	Column     string // Column the error relates to
+}
+
+func (e *Error) Error() string {
+	return e.common.Error() + ": " + e.cause.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	if e.timeout {
+		return []error{e.common, e.cause, db.ErrQueryTimeout}
+	}
+
+	return []error{e.common, e.cause}
+}
+
+// Code returns the common error code
+func (e *Error) Code() codes.Code {
+	return e.common.Code()
+}
+
+// WrapError translates known driver errors into *Error carrying a sys.CommonError.
+// Unknown errors and errors that are already translated are returned unchanged.
+func WrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var translated *Error
+	if errors.As(err, &translated) {
+		return err
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{
+			common: sys.NewCommonError("record not found", codes.NotFound),
+			cause:  err,
+		}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		if pgconn.Timeout(err) {
+			return &Error{
+				common:  sys.NewCommonError("query timeout", codes.DeadlineExceeded),
+				cause:   err,
+				timeout: true,
+			}
+		}
+
+		return err
+	}
+
+	var common *sys.CommonError
+	switch pgErr.Code {
+	case sqlStateUniqueViolation:
+		common = sys.NewCommonError("record already exists", codes.AlreadyExists)
+	case sqlStateForeignKeyViolation:
+		common = sys.NewCommonError("referenced record does not exist or is still referenced", codes.FailedPrecondition)
+	case sqlStateCheckViolation:
+		common = sys.NewCommonError("record violates check constraint", codes.FailedPrecondition)
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		common = sys.NewCommonError("transaction aborted due to concurrent update, retry", codes.Aborted)
+	case sqlStateQueryCanceled:
+		common = sys.NewCommonError("query canceled", codes.DeadlineExceeded)
+	default:
+		return err
+	}
+
+	return &Error{
+		common:     common,
+		cause:      err,
+		timeout:    pgErr.Code == sqlStateQueryCanceled,
+		SQLState:   pgErr.Code,
+		Constraint: pgErr.ConstraintName,
+		Table:      pgErr.TableName,
+		Column:     pgErr.ColumnName,
+	}
+}
@@ -0,0 +1,112 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
+	"github.com/WithSoull/platform_common/pkg/metric"
+	"github.com/WithSoull/platform_common/pkg/tracing"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultSpanName = "pg.query"
+
+// startQuery opens a client span for the named query and returns the function
+// that ends the span, records RED metrics and reports the query if it was slow.
+// The returned function must be called from the goroutine and call stack of the query caller.
+func (p *pg) startQuery(ctx context.Context, q db.Query, args ...any) (context.Context, func(err error)) {
+	return p.instrument(ctx, q, args, nil, true)
+}
+
+// startCopyQuery is startQuery for CopyFrom, whose synthetic COPY ... FROM STDIN statement cannot be explained
+func (p *pg) startCopyQuery(ctx context.Context, q db.Query) (context.Context, func(err error)) {
+	return p.instrument(ctx, q, nil, nil, false)
+}
+
+// startRowsQuery is startQuery for queries finished when their rows are closed,
+// possibly deep inside a scanning library, so the caller stack is recorded upfront.
+// The duration reported to metrics and the slow query log is the time spent in the database
+// measured by the returned clock, so the caller's per-row processing is not counted.
+func (p *pg) startRowsQuery(ctx context.Context, q db.Query, args ...any) (context.Context, func(err error), *fetchClock) {
+	clock := newFetchClock()
+	ctx, done := p.instrument(ctx, q, args, clock, true)
+	return ctx, done, clock
+}
+
+func (p *pg) instrument(ctx context.Context, q db.Query, args []any, clock *fetchClock, explainable bool) (context.Context, func(err error)) {
+	_, inTx := txctx.ExtractTx(ctx)
+
+	spanName := q.Name
+	if spanName == "" {
+		spanName = defaultSpanName
+	}
+
+	ctx, span := tracing.StartSpan(ctx, spanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBStatement(q.QueryRaw),
+			attribute.String("db.query.name", q.Name),
+			attribute.Bool("db.in_tx", inTx),
+		),
+	)
+	slow := p.watchSlowQuery(ctx, q, args, clock != nil, explainable)
+	start := time.Now()
+
+	return ctx, func(err error) {
+		duration := time.Since(start)
+		if clock != nil {
+			duration = clock.elapsed()
+		}
+
+		result := "success"
+		// An empty result is a valid outcome of the query, not a database failure
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			result = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		metric.ObserveDBQuery(ctx, q.Name, result, duration.Seconds())
+		slow.observe(ctx, duration)
+		span.End()
+	}
+}
+
+// fetchClock accumulates the time a rows query spends sending the query and fetching rows,
+// it is paused while the caller processes the current row
+type fetchClock struct {
+	busy    time.Duration
+	since   time.Time
+	running bool
+}
+
+func newFetchClock() *fetchClock {
+	return &fetchClock{since: time.Now(), running: true}
+}
+
+func (c *fetchClock) resume() {
+	if !c.running {
+		c.since = time.Now()
+		c.running = true
+	}
+}
+
+func (c *fetchClock) pause() {
+	if c.running {
+		c.busy += time.Since(c.since)
+		c.running = false
+	}
+}
+
+func (c *fetchClock) elapsed() time.Duration {
+	c.pause()
+	return c.busy
+}
@@ -0,0 +1,87 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	listenInitialBackoff = 100 * time.Millisecond
+	listenMaxBackoff     = 30 * time.Second
+)
+
+var notifyQuery = db.Query{Name: "pg.notify", QueryRaw: `SELECT pg_notify($1, $2)`}
+
+func (p *pg) Notify(ctx context.Context, channel, payload string) error {
+	_, err := p.ExecContext(ctx, notifyQuery, channel, payload)
+	return err
+}
+
+func (p *pg) Listen(ctx context.Context, handler db.NotificationHandler, channels ...string) error {
+	if len(channels) == 0 {
+		return errors.New("listen: no channels")
+	}
+
+	backoff := listenInitialBackoff
+	for {
+		subscribed, err := p.listen(ctx, handler, channels)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if subscribed {
+			backoff = listenInitialBackoff
+		}
+
+		p.l.Warn(ctx, "PG listener failed, reconnecting",
+			zap.Strings("channels", channels),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, listenMaxBackoff)
+	}
+}
+
+// listen runs a single subscription session and reports whether LISTEN succeeded before it ended
+func (p *pg) listen(ctx context.Context, handler db.NotificationHandler, channels []string) (bool, error) {
+	pooled, err := p.dbc.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// The connection keeps LISTEN state, so it is taken out of the pool and closed afterwards
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return false, errors.Wrapf(err, "listen %s", channel)
+		}
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		handler(ctx, db.Notification{
+			PID:     n.PID,
+			Channel: n.Channel,
+			Payload: n.Payload,
+		})
+	}
+}
@@ -2,9 +2,9 @@ package pg
 
 import (
 	"context"
-	"time"
 
 	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/client/db/named"
 	"github.com/WithSoull/platform_common/pkg/client/db/prettier"
 	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
 	"github.com/georgysavva/scany/pgxscan"
@@ -16,6 +16,7 @@ import (
 
 type Logger interface {
 	Debug(ctx context.Context, msg string, fields ...zap.Field)
+	Warn(ctx context.Context, msg string, fields ...zap.Field)
 }
 
 type pg struct {
@@ -32,25 +33,13 @@ func NewDB(dbc *pgxpool.Pool, logger Logger, cfg PGConfig) db.DB {
 	}
 }
 
-func (p *pg) withOpTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
-	if p.cfg == nil || p.cfg.Timeout() <= 0 {
-		return ctx, func() {}
-	}
-	if dl, ok := ctx.Deadline(); ok {
-		if time.Until(dl) <= p.cfg.Timeout() {
-			return ctx, func() {}
-		}
-	}
-	return context.WithTimeout(ctx, p.cfg.Timeout())
-}
-
 func (p *pg) ScanOneContext(ctx context.Context, dest any, q db.Query, args ...any) error {
 	row, err := p.QueryContext(ctx, q, args...)
 	if err != nil {
 		return err
 	}
 
-	return pgxscan.ScanOne(dest, row)
+	return WrapError(pgxscan.ScanOne(dest, row))
 }
 
 func (p *pg) ScanAllContext(ctx context.Context, dest any, q db.Query, args ...any) error {
@@ -59,73 +48,141 @@ func (p *pg) ScanAllContext(ctx context.Context, dest any, q db.Query, args ...a
 		return err
 	}
 
-	return pgxscan.ScanAll(dest, rows)
+	return WrapError(pgxscan.ScanAll(dest, rows))
 }
 
 func (p *pg) ExecContext(ctx context.Context, q db.Query, args ...any) (pgconn.CommandTag, error) {
-	ctx, cancel := p.withOpTimeout(ctx)
-	defer cancel()
+	q, args, err := bindNamed(q, args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, finish, err := p.queryScope(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer finish()
 
 	p.logQuery(ctx, q, args...)
 
+	ctx, done := p.startQuery(ctx, q, args...)
+
+	var tag pgconn.CommandTag
 	tx, ok := txctx.ExtractTx(ctx)
 	if ok {
-		return tx.Exec(ctx, q.QueryRaw, args...)
+		tag, err = tx.Exec(ctx, q.QueryRaw, args...)
+	} else {
+		tag, err = p.dbc.Exec(ctx, q.QueryRaw, args...)
 	}
 
-	return p.dbc.Exec(ctx, q.QueryRaw, args...)
+	err = WrapError(err)
+	done(err)
+
+	return tag, err
 }
 
 func (p *pg) QueryContext(ctx context.Context, q db.Query, args ...any) (pgx.Rows, error) {
-	ctx, cancel := p.withOpTimeout(ctx)
-	defer cancel()
+	q, args, err := bindNamed(q, args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, finish, err := p.queryScope(ctx, q)
+	if err != nil {
+		return nil, err
+	}
 
 	p.logQuery(ctx, q, args...)
 
+	ctx, done, clock := p.startRowsQuery(ctx, q, args...)
+	// The timeout context must outlive this call, it is released once the rows are closed
+	done = finishAfter(done, finish)
+
+	var r pgx.Rows
 	tx, ok := txctx.ExtractTx(ctx)
 	if ok {
-		return tx.Query(ctx, q.QueryRaw, args...)
+		r, err = tx.Query(ctx, q.QueryRaw, args...)
+	} else {
+		r, err = p.dbc.Query(ctx, q.QueryRaw, args...)
 	}
+	if err != nil {
+		err = WrapError(err)
+		done(err)
+		return nil, err
+	}
+
+	clock.pause()
 
-	return p.dbc.Query(ctx, q.QueryRaw, args...)
+	return &rows{Rows: r, done: done, clock: clock}, nil
 }
 
 func (p *pg) QueryRowContext(ctx context.Context, q db.Query, args ...any) pgx.Row {
+	q, args, err := bindNamed(q, args)
+	if err != nil {
+		return errRow{err: err}
+	}
+
+	ctx, finish, err := p.queryScope(ctx, q)
+	if err != nil {
+		return errRow{err: err}
+	}
+
 	p.logQuery(ctx, q, args...)
 
+	ctx, done := p.startQuery(ctx, q, args...)
+	done = finishAfter(done, finish)
+
 	tx, ok := txctx.ExtractTx(ctx)
 	if ok {
-		return tx.QueryRow(ctx, q.QueryRaw, args...)
+		return &row{Row: tx.QueryRow(ctx, q.QueryRaw, args...), done: done}
 	}
 
-	return p.dbc.QueryRow(ctx, q.QueryRaw, args...)
+	return &row{Row: p.dbc.QueryRow(ctx, q.QueryRaw, args...), done: done}
 }
 
 func (p *pg) Ping(ctx context.Context) error {
-	ctx, cancel := p.withOpTimeout(ctx)
+	ctx, cancel := p.withOpTimeout(ctx, 0)
 	defer cancel()
 
 	return p.dbc.QueryRow(ctx, "SELECT 1").Scan(new(int))
 }
 
 func (p *pg) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
-	ctx, cancel := p.withOpTimeout(ctx)
+	ctx, cancel := p.withOpTimeout(ctx, 0)
 	defer cancel()
 
 	return p.dbc.BeginTx(ctx, txOptions)
 }
 
+func (p *pg) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	return p.dbc.Acquire(ctx)
+}
+
 func (p *pg) Close() {
 	p.dbc.Close()
 }
 
+// bindNamed rewrites a query with :name parameters when its only argument is a named.Binder
+func bindNamed(q db.Query, args []any) (db.Query, []any, error) {
+	if len(args) != 1 {
+		return q, args, nil
+	}
+
+	binder, ok := args[0].(named.Binder)
+	if !ok {
+		return q, args, nil
+	}
+
+	return named.Bind(q, binder)
+}
+
 func (p *pg) logQuery(ctx context.Context, q db.Query, args ...any) {
 	if !p.cfg.NeedLog() {
 		return
 	}
 
 	_, inTx := txctx.ExtractTx(ctx)
-	prettyQuery := prettier.Pretty(q.QueryRaw, prettier.PlaceholderDollar, args...)
+	prettyQuery := prettier.PrettyQuery(q.Name, q.QueryRaw, prettier.PlaceholderDollar, args...)
 	p.l.Debug(ctx, "PG Query",
 		zap.String("name", q.Name),
 		zap.Bool("in_tx", inTx),
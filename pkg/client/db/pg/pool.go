@@ -0,0 +1,67 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/WithSoull/platform_common/pkg/metric"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+// poolConfig parses dsn and applies pool settings when cfg implements PoolConfig
+func poolConfig(dsn string, cfg PGConfig) (*pgxpool.Config, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	pc, ok := cfg.(PoolConfig)
+	if !ok {
+		return poolCfg, nil
+	}
+
+	if v := pc.MaxConns(); v > 0 {
+		poolCfg.MaxConns = v
+	}
+	if v := pc.MinConns(); v > 0 {
+		poolCfg.MinConns = v
+	}
+	if v := pc.MaxConnLifetime(); v > 0 {
+		poolCfg.MaxConnLifetime = v
+	}
+	if v := pc.MaxConnIdleTime(); v > 0 {
+		poolCfg.MaxConnIdleTime = v
+	}
+	if v := pc.HealthCheckPeriod(); v > 0 {
+		poolCfg.HealthCheckPeriod = v
+	}
+
+	return poolCfg, nil
+}
+
+// registerPoolMetrics publishes pool.Stat() through pkg/metric under the given pool name and the pool database.
+// A pool that duplicates one of another client is not published, so that their series are not mixed.
+func registerPoolMetrics(ctx context.Context, logger Logger, name string, pool *pgxpool.Pool) func() {
+	conn := pool.Config().ConnConfig
+	dbName := fmt.Sprintf("%s:%d/%s", conn.Host, conn.Port, conn.Database)
+
+	unregister, err := metric.RegisterDBPool(name, dbName, func() metric.DBPoolStats {
+		s := pool.Stat()
+		return metric.DBPoolStats{
+			AcquiredConns:   int64(s.AcquiredConns()),
+			IdleConns:       int64(s.IdleConns()),
+			TotalConns:      int64(s.TotalConns()),
+			MaxConns:        int64(s.MaxConns()),
+			AcquireCount:    s.AcquireCount(),
+			AcquireDuration: s.AcquireDuration(),
+			EmptyAcquires:   s.EmptyAcquireCount(),
+		}
+	})
+	if err != nil {
+		logger.Warn(ctx, "PG pool metrics are not exported", zap.Error(err))
+		return func() {}
+	}
+
+	return unregister
+}
@@ -0,0 +1,246 @@
+package pg
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/contextx/readonlyctx"
+	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Replica balancing strategies
+const (
+	BalancerRoundRobin      = "round_robin"
+	BalancerLeastConnection = "least_conn"
+)
+
+const defaultReplicaCheckInterval = 5 * time.Second
+
+// replicationLagQuery returns replay lag in seconds, 0 when the server is not a standby
+// or is streaming and has replayed everything it received: the last replay timestamp keeps aging while the primary is idle.
+// A standby whose WAL receiver is not streaming stops receiving, so its lag is always measured from the replay timestamp.
+const replicationLagQuery = `
+SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn()
+		AND EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming') THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8`
+
+type replica struct {
+	index   int
+	db      db.DB
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// routedDB sends writes and transactions to the primary and spreads reads over healthy replicas
+type routedDB struct {
+	primary  db.DB
+	replicas []*replica
+	l        Logger
+	cfg      ReplicaConfig
+
+	next   atomic.Uint64
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newRoutedDB(ctx context.Context, primary db.DB, pools []*pgxpool.Pool, logger Logger, cfg PGConfig, replicaCfg ReplicaConfig) *routedDB {
+	r := &routedDB{
+		primary: primary,
+		l:       logger,
+		cfg:     replicaCfg,
+	}
+
+	for i, pool := range pools {
+		r.replicas = append(r.replicas, &replica{
+			index: i,
+			db:    NewDB(pool, logger, cfg),
+			pool:  pool,
+		})
+	}
+
+	r.checkReplicas(ctx)
+
+	checkCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go r.watchReplicas(checkCtx)
+
+	return r
+}
+
+// reader picks a database for a query: a healthy replica when the query or the context is marked read-only
+// and no transaction is running, the primary otherwise. Unmarked queries stay on the primary,
+// since they may write, e.g. INSERT ... RETURNING, or read data the caller has just written.
+func (r *routedDB) reader(ctx context.Context, readOnly bool) db.DB {
+	if _, inTx := txctx.ExtractTx(ctx); inTx {
+		return r.primary
+	}
+	if !readOnly && !readonlyctx.IsReadOnly(ctx) {
+		return r.primary
+	}
+
+	if rep := r.pick(); rep != nil {
+		return rep.db
+	}
+
+	return r.primary
+}
+
+func (r *routedDB) pick() *replica {
+	healthy := make([]*replica, 0, len(r.replicas))
+	for _, rep := range r.replicas {
+		if rep.healthy.Load() {
+			healthy = append(healthy, rep)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	if r.cfg.ReplicaBalancer() == BalancerLeastConnection {
+		best := healthy[0]
+		for _, rep := range healthy[1:] {
+			if rep.pool.Stat().AcquiredConns() < best.pool.Stat().AcquiredConns() {
+				best = rep
+			}
+		}
+		return best
+	}
+
+	return healthy[r.next.Add(1)%uint64(len(healthy))]
+}
+
+func (r *routedDB) watchReplicas(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.checkInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.checkReplicas(ctx)
+		}
+	}
+}
+
+// checkInterval also bounds a single replica check, so that a hung replica cannot stall the checks
+func (r *routedDB) checkInterval() time.Duration {
+	if interval := r.cfg.ReplicaCheckInterval(); interval > 0 {
+		return interval
+	}
+	return defaultReplicaCheckInterval
+}
+
+func (r *routedDB) checkReplicas(ctx context.Context) {
+	for _, rep := range r.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, r.checkInterval())
+		err := r.checkReplica(checkCtx, rep)
+		cancel()
+		healthy := err == nil
+
+		if rep.healthy.Swap(healthy) == healthy {
+			continue
+		}
+
+		if healthy {
+			r.l.Warn(ctx, "PG replica added to rotation", zap.Int("replica", rep.index))
+		} else {
+			r.l.Warn(ctx, "PG replica removed from rotation", zap.Int("replica", rep.index), zap.Error(err))
+		}
+	}
+}
+
+func (r *routedDB) checkReplica(ctx context.Context, rep *replica) error {
+	if err := rep.db.Ping(ctx); err != nil {
+		return err
+	}
+
+	maxLag := r.cfg.ReplicaMaxLag()
+	if maxLag <= 0 {
+		return nil
+	}
+
+	var lagSeconds float64
+	if err := rep.db.QueryRowContext(ctx, db.Query{Name: "pg.replication_lag", QueryRaw: replicationLagQuery}).Scan(&lagSeconds); err != nil {
+		return err
+	}
+
+	if lag := time.Duration(lagSeconds * float64(time.Second)); lag > maxLag {
+		return errors.Errorf("replication lag %s exceeds %s", lag, maxLag)
+	}
+
+	return nil
+}
+
+func (r *routedDB) ScanOneContext(ctx context.Context, dest any, q db.Query, args ...any) error {
+	return r.reader(ctx, q.ReadOnly).ScanOneContext(ctx, dest, q, args...)
+}
+
+func (r *routedDB) ScanAllContext(ctx context.Context, dest any, q db.Query, args ...any) error {
+	return r.reader(ctx, q.ReadOnly).ScanAllContext(ctx, dest, q, args...)
+}
+
+func (r *routedDB) ExecContext(ctx context.Context, q db.Query, args ...any) (pgconn.CommandTag, error) {
+	return r.primary.ExecContext(ctx, q, args...)
+}
+
+func (r *routedDB) QueryContext(ctx context.Context, q db.Query, args ...any) (pgx.Rows, error) {
+	return r.reader(ctx, q.ReadOnly).QueryContext(ctx, q, args...)
+}
+
+func (r *routedDB) QueryRowContext(ctx context.Context, q db.Query, args ...any) pgx.Row {
+	return r.reader(ctx, q.ReadOnly).QueryRowContext(ctx, q, args...)
+}
+
+func (r *routedDB) SendBatch(ctx context.Context, b *db.Batch) ([]db.BatchResult, error) {
+	return r.primary.SendBatch(ctx, b)
+}
+
+func (r *routedDB) CopyFrom(ctx context.Context, q db.CopyQuery, src pgx.CopyFromSource) (int64, error) {
+	return r.primary.CopyFrom(ctx, q, src)
+}
+
+func (r *routedDB) Ping(ctx context.Context) error {
+	return r.primary.Ping(ctx)
+}
+
+func (r *routedDB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	return r.primary.BeginTx(ctx, txOptions)
+}
+
+func (r *routedDB) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	return r.primary.Acquire(ctx)
+}
+
+func (r *routedDB) Listen(ctx context.Context, handler db.NotificationHandler, channels ...string) error {
+	return r.primary.Listen(ctx, handler, channels...)
+}
+
+func (r *routedDB) Notify(ctx context.Context, channel, payload string) error {
+	return r.primary.Notify(ctx, channel, payload)
+}
+
+func (r *routedDB) Close() {
+	r.cancel()
+	r.wg.Wait()
+
+	for _, rep := range r.replicas {
+		rep.db.Close()
+	}
+	r.primary.Close()
+}
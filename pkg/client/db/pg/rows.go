@@ -0,0 +1,79 @@
+package pg
+
+import (
+	"github.com/jackc/pgx/v4"
+)
+
+// rows translates errors reported while iterating over query results
+// and finishes query instrumentation once the result set is closed.
+// Only time spent in Next and Close, i.e. fetching rows, counts towards the query duration.
+type rows struct {
+	pgx.Rows
+	done  func(err error)
+	clock *fetchClock
+}
+
+func (r *rows) Scan(dest ...any) error {
+	return WrapError(r.Rows.Scan(dest...))
+}
+
+func (r *rows) Err() error {
+	return WrapError(r.Rows.Err())
+}
+
+func (r *rows) Next() bool {
+	r.clock.resume()
+	next := r.Rows.Next()
+	r.clock.pause()
+	if next {
+		return true
+	}
+
+	// pgx closes rows automatically once they are exhausted
+	r.finish()
+	return false
+}
+
+func (r *rows) Close() {
+	// Closing early drains the remaining rows from the connection
+	r.clock.resume()
+	r.Rows.Close()
+	r.clock.pause()
+	r.finish()
+}
+
+func (r *rows) finish() {
+	if r.done == nil {
+		return
+	}
+
+	done := r.done
+	r.done = nil
+	done(r.Err())
+}
+
+// row translates errors reported on scanning a single row
+// and finishes query instrumentation after the scan
+type row struct {
+	pgx.Row
+	done func(err error)
+}
+
+func (r *row) Scan(dest ...any) error {
+	err := WrapError(r.Row.Scan(dest...))
+	if r.done != nil {
+		r.done(err)
+		r.done = nil
+	}
+
+	return err
+}
+
+// errRow reports an error that occurred before the query was sent
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
@@ -0,0 +1,133 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/client/db/prettier"
+	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
+	"go.uber.org/zap"
+)
+
+const (
+	explainTimeout = 5 * time.Second
+	// dbPackagePrefix covers db, pg and transaction frames skipped when looking for the caller
+	dbPackagePrefix = "github.com/WithSoull/platform_common/pkg/client/db"
+)
+
+const maxCallerDepth = 16
+
+// slowQueryWatch captures what is needed to report a query that exceeds the slow query threshold
+type slowQueryWatch struct {
+	p    *pg
+	q    db.Query
+	args []any
+	inTx bool
+
+	// explainable is false for operations whose query text is not a statement, e.g. COPY FROM STDIN
+	explainable bool
+
+	// pcs holds the stack captured when the query started, it is set only for queries
+	// finished outside the caller's frames, e.g. rows closed by a scanning library
+	pcs []uintptr
+}
+
+// watchSlowQuery returns nil when slow query logging is disabled.
+// With captureStack the program counters of the caller are recorded now and resolved only if the query is slow,
+// otherwise the caller is looked up when the query finishes. EXPLAIN is never sampled for non-explainable queries.
+func (p *pg) watchSlowQuery(ctx context.Context, q db.Query, args []any, captureStack, explainable bool) *slowQueryWatch {
+	if p.cfg == nil || slowQueryThreshold(p.cfg) <= 0 {
+		return nil
+	}
+
+	_, inTx := txctx.ExtractTx(ctx)
+	w := &slowQueryWatch{
+		p:           p,
+		q:           q,
+		args:        args,
+		inTx:        inTx,
+		explainable: explainable,
+	}
+	if captureStack {
+		w.pcs = callerPCs()
+	}
+
+	return w
+}
+
+// observe logs the query at warn level when its duration exceeds the threshold
+func (w *slowQueryWatch) observe(ctx context.Context, duration time.Duration) {
+	if w == nil || duration < slowQueryThreshold(w.p.cfg) {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("name", w.q.Name),
+		zap.Bool("in_tx", w.inTx),
+		zap.Duration("duration", duration),
+		zap.String("caller", w.caller()),
+		zap.String("query", prettier.PrettyQuery(w.q.Name, w.q.QueryRaw, prettier.PlaceholderDollar, w.args...)),
+	}
+
+	rate := slowQueryExplainRate(w.p.cfg)
+	if !w.explainable || rate <= 0 || rand.Float64() >= rate {
+		w.p.l.Warn(ctx, "PG slow query", fields...)
+		return
+	}
+
+	// EXPLAIN runs on its own pool connection, outside the original transaction,
+	// so it must not block or outlive the caller's request
+	go func() {
+		explainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), explainTimeout)
+		defer cancel()
+
+		var plan string
+		err := w.p.dbc.QueryRow(explainCtx, "EXPLAIN (FORMAT JSON) "+w.q.QueryRaw, w.args...).Scan(&plan)
+		if err != nil {
+			fields = append(fields, zap.NamedError("explain_error", err))
+		} else {
+			fields = append(fields, zap.String("plan", plan))
+		}
+
+		w.p.l.Warn(ctx, "PG slow query", fields...)
+	}()
+}
+
+// caller returns file:line of the code that ran the query
+func (w *slowQueryWatch) caller() string {
+	if w.pcs != nil {
+		return queryCaller(w.pcs)
+	}
+
+	return queryCaller(callerPCs())
+}
+
+func callerPCs() []uintptr {
+	pcs := make([]uintptr, maxCallerDepth)
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// queryCaller returns file:line of the first frame outside the db packages
+func queryCaller(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return "unknown"
+	}
+
+	frames := runtime.CallersFrames(pcs)
+
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, dbPackagePrefix) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
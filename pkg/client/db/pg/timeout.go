@@ -0,0 +1,89 @@
+package pg
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	setStatementTimeoutQuery     = `SELECT current_setting('statement_timeout'), set_config('statement_timeout', $1, true)`
+	restoreStatementTimeoutQuery = `SELECT set_config('statement_timeout', $1, true)`
+)
+
+// withOpTimeout bounds ctx by the client-wide timeout and queryTimeout, whichever is shorter
+func (p *pg) withOpTimeout(ctx context.Context, queryTimeout time.Duration) (context.Context, context.CancelFunc) {
+	var timeout time.Duration
+	if p.cfg != nil {
+		timeout = p.cfg.Timeout()
+	}
+	if queryTimeout > 0 && (timeout <= 0 || queryTimeout < timeout) {
+		timeout = queryTimeout
+	}
+
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		if time.Until(dl) <= timeout {
+			return ctx, func() {}
+		}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// queryScope applies timeouts of q and returns the context to run it with
+// along with a func to call once its results are consumed.
+// Inside a transaction q.Timeout is enforced by the server for this query only.
+func (p *pg) queryScope(ctx context.Context, q db.Query) (context.Context, func(), error) {
+	tx, inTx := txctx.ExtractTx(ctx)
+	if !inTx {
+		ctx, cancel := p.withOpTimeout(ctx, q.Timeout)
+		return ctx, cancel, nil
+	}
+
+	ctx, cancel := p.withOpTimeout(ctx, 0)
+	restore, err := setStatementTimeout(ctx, tx, q.Timeout)
+	if err != nil {
+		cancel()
+		return nil, nil, WrapError(err)
+	}
+
+	return ctx, func() {
+		restore()
+		cancel()
+	}, nil
+}
+
+// setStatementTimeout sets statement_timeout local to tx and returns a func restoring the previous value
+func setStatementTimeout(ctx context.Context, tx pgx.Tx, timeout time.Duration) (func(), error) {
+	if timeout <= 0 {
+		return func() {}, nil
+	}
+
+	// statement_timeout = 0 disables the timeout, so it is never rounded down to it
+	ms := strconv.FormatInt(max(timeout.Milliseconds(), 1), 10)
+
+	var prev, applied string
+	if err := tx.QueryRow(ctx, setStatementTimeoutQuery, ms).Scan(&prev, &applied); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		// Fails only when the transaction is already aborted, e.g. by the timeout itself,
+		// its rollback discards the setting anyway
+		_, _ = tx.Exec(context.WithoutCancel(ctx), restoreStatementTimeoutQuery, prev)
+	}, nil
+}
+
+// finishAfter returns done that also calls finish once the query results are consumed
+func finishAfter(done func(err error), finish func()) func(err error) {
+	return func(err error) {
+		done(err)
+		finish()
+	}
+}
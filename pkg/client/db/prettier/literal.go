@@ -0,0 +1,138 @@
+package prettier
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Literal renders v as a SQL literal.
+// driver.Valuer implementations, including pgtype values, are rendered by their driver value.
+func Literal(v any) string {
+	return literal(v, 0)
+}
+
+// maxValuerDepth guards against Valuer implementations returning themselves
+const maxValuerDepth = 8
+
+func literal(v any, depth int) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quote(v)
+	case []byte:
+		if v == nil {
+			return "NULL"
+		}
+		return `'\x` + hex.EncodeToString(v) + `'`
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return formatFloat(float64(v), 32)
+	case float64:
+		return formatFloat(v, 64)
+	case time.Time:
+		return quote(v.Format("2006-01-02 15:04:05.999999Z07:00"))
+	case time.Duration:
+		return quote(strconv.FormatInt(v.Microseconds(), 10) + " microseconds")
+	case driver.Valuer:
+		if depth >= maxValuerDepth {
+			return quote(fmt.Sprint(v))
+		}
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "NULL"
+		}
+		value, err := v.Value()
+		if err != nil {
+			return quote(fmt.Sprint(v))
+		}
+		return literal(value, depth+1)
+	}
+
+	// Named basic types are sent by pgx as their underlying value, even when they implement fmt.Stringer
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return quote(rv.String())
+	case reflect.Bool:
+		return literal(rv.Bool(), depth)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return formatFloat(rv.Float(), 64)
+	}
+
+	if stringer, ok := v.(fmt.Stringer); ok {
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "NULL"
+		}
+		return quote(stringer.String())
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return "NULL"
+		}
+		return literal(rv.Elem().Interface(), depth)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return "NULL"
+		}
+		if rv.Len() == 0 {
+			return "'{}'"
+		}
+		items := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, literal(rv.Index(i).Interface(), depth))
+		}
+		return "ARRAY[" + strings.Join(items, ",") + "]"
+	}
+
+	return quote(fmt.Sprintf("%v", v))
+}
+
+// quote renders s as a standard-conforming string literal
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func formatFloat(f float64, bitSize int) string {
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	// NaN and infinities are only valid as quoted literals
+	if s == "NaN" || s == "+Inf" || s == "-Inf" {
+		return quote(strings.TrimPrefix(strings.Replace(s, "Inf", "Infinity", 1), "+"))
+	}
+
+	return s
+}
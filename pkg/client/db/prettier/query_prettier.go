@@ -1,9 +1,10 @@
 package prettier
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/WithSoull/platform_common/pkg/client/db/internal/sqlscan"
 )
 
 const (
@@ -11,23 +12,86 @@ const (
 	PlaceholderQuestion = "?"
 )
 
+// Pretty renders the query on a single line with placeholders replaced by SQL literals.
+// Only redaction rules by value type apply, use PrettyQuery to apply rules by query name.
 func Pretty(query string, placeholder string, args ...any) string {
-	for i, param := range args {
-		var value string
-		switch v := param.(type) {
-		case string:
-			value = fmt.Sprintf("%q", v)
-		case []byte:
-			value = fmt.Sprintf("%q", string(v))
+	return PrettyQuery("", query, placeholder, args...)
+}
+
+// PrettyQuery is Pretty applying redaction rules registered for the query name.
+// String literals, quoted identifiers, dollar-quoted strings and comments are copied as is,
+// whitespace outside them is collapsed.
+func PrettyQuery(name, query string, placeholder string, args ...any) string {
+	r := renderer{name: name, args: args, rules: currentRules()}
+
+	var sb strings.Builder
+	sb.Grow(len(query))
+
+	space := false
+	next := 0 // index of the next ? placeholder
+	for i := 0; i < len(query); {
+		c := query[i]
+		if isSpace(c) {
+			space = true
+			i++
+			continue
+		}
+		if space && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		space = false
+
+		tok := sqlscan.Next(query, i)
+		i = tok.End()
+
+		switch {
+		case tok.Kind == sqlscan.LineComment:
+			// a line comment would swallow the rest of the single-line query
+			comment := strings.TrimSpace(strings.ReplaceAll(tok.Text[2:], "*/", "* /"))
+			sb.WriteString("/* " + comment + " */")
+
+		case tok.Kind == sqlscan.Positional && placeholder == PlaceholderDollar:
+			n, err := strconv.Atoi(tok.Text[1:])
+			if err != nil || !r.write(&sb, n) {
+				sb.WriteString(tok.Text)
+			}
+
+		case tok.Kind == sqlscan.Char && c == '?' && placeholder == PlaceholderQuestion:
+			next++
+			if !r.write(&sb, next) {
+				sb.WriteByte('?')
+			}
+
 		default:
-			value = fmt.Sprintf("%v", v)
+			sb.WriteString(tok.Text)
 		}
+	}
+
+	return sb.String()
+}
 
-		query = strings.Replace(query, fmt.Sprintf("%s%s", placeholder, strconv.Itoa(i+1)), value, -1)
+type renderer struct {
+	name  string
+	args  []any
+	rules *rules
+}
+
+// write renders the n-th (1-based) argument, it reports false when there is no such argument
+func (r renderer) write(sb *strings.Builder, n int) bool {
+	if n < 1 || n > len(r.args) {
+		return false
 	}
 
-	query = strings.ReplaceAll(query, "\t", "")
-	query = strings.ReplaceAll(query, "\n", " ")
+	v := r.args[n-1]
+	if r.rules.redacted(r.name, n, v) {
+		sb.WriteString(RedactedValue)
+		return true
+	}
+
+	sb.WriteString(Literal(v))
+	return true
+}
 
-	return strings.TrimSpace(query)
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
 }
@@ -0,0 +1,108 @@
+package prettier
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// RedactedValue replaces masked arguments in rendered queries
+const RedactedValue = "'***'"
+
+type rules struct {
+	positions map[string]map[int]struct{} // Query.Name -> masked 1-based argument positions
+	types     map[reflect.Type]struct{}
+}
+
+var (
+	rulesMu sync.Mutex
+	active  atomic.Pointer[rules]
+)
+
+func currentRules() *rules {
+	return active.Load()
+}
+
+// update applies fn to a copy of the active rules, so rendering never takes a lock
+func update(fn func(r *rules)) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+
+	next := &rules{
+		positions: make(map[string]map[int]struct{}),
+		types:     make(map[reflect.Type]struct{}),
+	}
+	if cur := active.Load(); cur != nil {
+		for name, positions := range cur.positions {
+			next.positions[name] = positions
+		}
+		for t := range cur.types {
+			next.types[t] = struct{}{}
+		}
+	}
+
+	fn(next)
+	active.Store(next)
+}
+
+// RedactArgs masks arguments of the named query at the given 1-based positions, i.e. $1 is position 1.
+// For :name queries positions follow the first appearance of each parameter.
+func RedactArgs(queryName string, positions ...int) {
+	update(func(r *rules) {
+		masked := make(map[int]struct{}, len(r.positions[queryName])+len(positions))
+		for pos := range r.positions[queryName] {
+			masked[pos] = struct{}{}
+		}
+		for _, pos := range positions {
+			masked[pos] = struct{}{}
+		}
+		r.positions[queryName] = masked
+	})
+}
+
+// RedactType masks every argument with the dynamic type of sample, pointers to it included.
+// It suits dedicated secret types, e.g. RedactType(PasswordHash("")).
+func RedactType(sample any) {
+	if sample == nil {
+		return
+	}
+
+	update(func(r *rules) {
+		r.types[reflect.TypeOf(sample)] = struct{}{}
+	})
+}
+
+// ResetRedaction removes all redaction rules
+func ResetRedaction() {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+
+	active.Store(nil)
+}
+
+func (r *rules) redacted(name string, pos int, v any) bool {
+	if r == nil {
+		return false
+	}
+
+	if name != "" {
+		if _, ok := r.positions[name][pos]; ok {
+			return true
+		}
+	}
+
+	if v == nil || len(r.types) == 0 {
+		return false
+	}
+
+	t := reflect.TypeOf(v)
+	for {
+		if _, ok := r.types[t]; ok {
+			return true
+		}
+		if t.Kind() != reflect.Pointer {
+			return false
+		}
+		t = t.Elem()
+	}
+}
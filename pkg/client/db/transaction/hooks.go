@@ -0,0 +1,61 @@
+package transaction
+
+import (
+	"context"
+
+	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
+	"github.com/pkg/errors"
+)
+
+// ErrNoTransaction is returned when a hook is registered outside a TxManager transaction
+var ErrNoTransaction = errors.New("transaction: hook must be registered inside a transaction")
+
+// AfterCommit registers fn to run after the outermost transaction in ctx commits.
+// Hooks registered inside a nested call that rolled back to its savepoint are dropped.
+// fn receives the context the outermost transaction was started with.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) error {
+	hooks, ok := txctx.ExtractHooks(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	hooks.AfterCommit(fn)
+	return nil
+}
+
+// AfterRollback registers fn to run after the outermost transaction in ctx rolls back
+// and is not retried anymore
+func AfterRollback(ctx context.Context, fn func(ctx context.Context)) error {
+	hooks, ok := txctx.ExtractHooks(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	hooks.AfterRollback(fn)
+	return nil
+}
+
+// WithHookPanicHandler sets a handler for panics recovered from transaction hooks,
+// by default they are ignored
+func WithHookPanicHandler(fn func(ctx context.Context, recovered any)) Option {
+	return func(m *manager) {
+		m.onHookPanic = fn
+	}
+}
+
+// runHooks calls each hook, a panicking hook does not stop the others nor change the transaction result
+func (m *manager) runHooks(ctx context.Context, hooks []txctx.Hook) {
+	for _, hook := range hooks {
+		m.runHook(ctx, hook)
+	}
+}
+
+func (m *manager) runHook(ctx context.Context, hook txctx.Hook) {
+	defer func() {
+		if r := recover(); r != nil && m.onHookPanic != nil {
+			m.onHookPanic(ctx, r)
+		}
+	}()
+
+	hook(ctx)
+}
@@ -0,0 +1,85 @@
+package transaction
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/pkg/errors"
+)
+
+// SQLSTATE codes after which the whole transaction can be safely re-run
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 10 * time.Millisecond
+	defaultMaxDelay    = 500 * time.Millisecond
+)
+
+// Option configures the transaction manager
+type Option func(m *manager)
+
+// WithRetry sets how many times a transaction is attempted in total when it fails
+// with a serialization failure or a deadlock, and the bounds of the jittered backoff between attempts.
+// maxAttempts <= 1 disables retries.
+func WithRetry(maxAttempts int, baseDelay, maxDelay time.Duration) Option {
+	return func(m *manager) {
+		m.maxAttempts = maxAttempts
+		m.baseDelay = baseDelay
+		m.maxDelay = maxDelay
+	}
+}
+
+// WithoutRetry disables re-running transactions on serialization failures and deadlocks
+func WithoutRetry() Option {
+	return WithRetry(1, 0, 0)
+}
+
+// IsRetryable reports whether err is a serialization failure or a deadlock
+// after which the transaction can be re-run from the beginning
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == sqlStateSerializationFailure || pgErr.Code == sqlStateDeadlockDetected
+}
+
+// withRetry runs fn until it succeeds, fails with a non-retryable error or attempts are exhausted
+func (m *manager) withRetry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= m.maxAttempts || !IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(m.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Wrapf(err, "transaction retry interrupted: %v", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns a full-jitter exponential delay for the given attempt
+func (m *manager) backoff(attempt int) time.Duration {
+	if m.baseDelay <= 0 {
+		return 0
+	}
+
+	delay := m.baseDelay << (attempt - 1)
+	if delay <= 0 || (m.maxDelay > 0 && delay > m.maxDelay) {
+		delay = m.maxDelay
+	}
+
+	return time.Duration(rand.Int64N(int64(delay) + 1))
+}
@@ -2,6 +2,7 @@ package transaction
 
 import (
 	"context"
+	"time"
 
 	"github.com/WithSoull/platform_common/pkg/client/db"
 	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
@@ -11,25 +12,59 @@ import (
 
 type manager struct {
 	db db.Transactor
+
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+
+	onHookPanic func(ctx context.Context, recovered any)
 }
 
 // NewTransactionManager creates a new transaction manager that implements db.TxManager interface
-func NewTransactionManager(db db.Transactor) db.TxManager {
-	return &manager{
-		db: db,
+func NewTransactionManager(db db.Transactor, opts ...Option) db.TxManager {
+	m := &manager{
+		db:          db,
+		maxAttempts: defaultMaxAttempts,
+		baseDelay:   defaultBaseDelay,
+		maxDelay:    defaultMaxDelay,
+	}
+
+	for _, opt := range opts {
+		opt(m)
 	}
+
+	return m
 }
 
-// transaction is the main function that executes user-provided handler within a transaction
-func (m *manager) transaction(ctx context.Context, opts pgx.TxOptions, fn db.Handler) (err error) {
-	// If this is a nested transaction, skip initiating new transaction and execute handler
-	tx, ok := txctx.ExtractTx(ctx)
-	if ok {
-		return fn(ctx)
+// transaction executes user-provided handler within a transaction.
+// The outermost transaction is re-run as a whole on serialization failures and deadlocks,
+// its hooks run once the final attempt has committed or rolled back.
+func (m *manager) transaction(ctx context.Context, opts pgx.TxOptions, fn db.Handler) error {
+	// If this is a nested transaction, run handler inside a savepoint of the outer one
+	if tx, ok := txctx.ExtractTx(ctx); ok {
+		return m.savepoint(ctx, tx, fn)
 	}
 
+	var hooks *txctx.Hooks
+	err := m.withRetry(ctx, func() error {
+		// Hooks of a retried attempt are discarded, the handler registers them again
+		hooks = txctx.NewHooks()
+		return m.run(txctx.InjectHooks(ctx, hooks), opts, fn)
+	})
+
+	if err != nil {
+		m.runHooks(ctx, hooks.RolledBack())
+		return err
+	}
+
+	m.runHooks(ctx, hooks.Committed())
+	return nil
+}
+
+// run executes a single attempt of the handler within a new transaction
+func (m *manager) run(ctx context.Context, opts pgx.TxOptions, fn db.Handler) (err error) {
 	// Start new transaction
-	tx, err = m.db.BeginTx(ctx, opts)
+	tx, err := m.db.BeginTx(ctx, opts)
 	if err != nil {
 		return errors.Wrap(err, "can't begin transaction")
 	}
@@ -72,7 +107,74 @@ func (m *manager) transaction(ctx context.Context, opts pgx.TxOptions, fn db.Han
 	return err
 }
 
+// savepoint executes handler within a savepoint of the outer transaction,
+// so that its failure rolls back only the nested unit of work
+func (m *manager) savepoint(ctx context.Context, tx pgx.Tx, fn db.Handler) (err error) {
+	ctx, sp := txctx.InjectSavepoint(ctx)
+
+	hooks, ok := txctx.ExtractHooks(ctx)
+	if !ok {
+		hooks = txctx.NewHooks()
+	}
+	hooks = hooks.Scope()
+	ctx = txctx.InjectHooks(ctx, hooks)
+
+	if _, err = tx.Exec(ctx, "SAVEPOINT "+sp.Name); err != nil {
+		return errors.Wrapf(err, "can't create savepoint %s", sp.Name)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("panic recovered: %v", r)
+		}
+
+		// Rollback to savepoint if error occurred, outer transaction stays usable
+		if err != nil {
+			hooks.Discard()
+			if _, errRollback := tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+sp.Name); errRollback != nil {
+				err = errors.Wrapf(err, "errRollback to savepoint %s: %v", sp.Name, errRollback)
+			}
+
+			return
+		}
+
+		if _, err = tx.Exec(ctx, "RELEASE SAVEPOINT "+sp.Name); err != nil {
+			hooks.Discard()
+			err = errors.Wrapf(err, "release savepoint %s failed", sp.Name)
+			return
+		}
+
+		hooks.Release()
+	}()
+
+	if err = fn(ctx); err != nil {
+		err = errors.Wrap(err, "failed executing code inside savepoint")
+	}
+
+	return err
+}
+
 func (m *manager) ReadCommitted(ctx context.Context, f db.Handler) error {
 	txOpts := pgx.TxOptions{IsoLevel: pgx.ReadCommitted}
 	return m.transaction(ctx, txOpts, f)
 }
+
+func (m *manager) RepeatableRead(ctx context.Context, f db.Handler) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead}
+	return m.transaction(ctx, txOpts, f)
+}
+
+func (m *manager) Serializable(ctx context.Context, f db.Handler) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.Serializable}
+	return m.transaction(ctx, txOpts, f)
+}
+
+func (m *manager) ReadOnly(ctx context.Context, f db.Handler) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	return m.transaction(ctx, txOpts, f)
+}
+
+func (m *manager) SerializableReadOnly(ctx context.Context, f db.Handler) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly, DeferrableMode: pgx.Deferrable}
+	return m.transaction(ctx, txOpts, f)
+}
@@ -24,6 +24,11 @@ func InjectIp(ctx context.Context) context.Context {
 	return context.WithValue(ctx, IpKey, ip)
 }
 
+// InjectIpValue puts an already known client IP into the context, e.g. one received from another service
+func InjectIpValue(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, IpKey, ip)
+}
+
 func ExtractIP(ctx context.Context) (string, bool) {
 	if ip, ok := ctx.Value(IpKey).(string); ok {
 		return ip, true
@@ -0,0 +1,28 @@
+package readonlyctx
+
+import (
+	"context"
+
+	"github.com/WithSoull/platform_common/pkg/contextx"
+)
+
+const ReadOnlyKey contextx.CtxKey = "read_only"
+
+// InjectReadOnly marks the context as read-only, so that queries made outside
+// a transaction may be served by a replica
+func InjectReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ReadOnlyKey, true)
+}
+
+func ExtractReadOnly(ctx context.Context) (bool, bool) {
+	if readOnly, ok := ctx.Value(ReadOnlyKey).(bool); ok {
+		return readOnly, true
+	}
+	return false, false
+}
+
+// IsReadOnly reports whether the context is marked read-only
+func IsReadOnly(ctx context.Context) bool {
+	readOnly, _ := ExtractReadOnly(ctx)
+	return readOnly
+}
@@ -0,0 +1,103 @@
+package txctx
+
+import (
+	"context"
+	"sync"
+
+	"github.com/WithSoull/platform_common/pkg/contextx"
+)
+
+const HooksKey contextx.CtxKey = "tx_hooks"
+
+// Hook is a callback run once the outermost transaction has finished
+type Hook func(ctx context.Context)
+
+// Hooks collects callbacks of a transaction or of a savepoint nested in it
+type Hooks struct {
+	mu     sync.Mutex
+	parent *Hooks
+
+	afterCommit   []Hook
+	afterRollback []Hook
+}
+
+// NewHooks returns hooks of an outermost transaction
+func NewHooks() *Hooks {
+	return &Hooks{}
+}
+
+// Scope returns hooks of a savepoint nested in h
+func (h *Hooks) Scope() *Hooks {
+	return &Hooks{parent: h}
+}
+
+func (h *Hooks) root() *Hooks {
+	for h.parent != nil {
+		h = h.parent
+	}
+	return h
+}
+
+// AfterCommit adds fn to run after the outermost transaction commits.
+// It is dropped if the savepoint it was added in is rolled back.
+func (h *Hooks) AfterCommit(fn Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.afterCommit = append(h.afterCommit, fn)
+}
+
+// AfterRollback adds fn to run after the outermost transaction rolls back
+func (h *Hooks) AfterRollback(fn Hook) {
+	root := h.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	root.afterRollback = append(root.afterRollback, fn)
+}
+
+// Release moves after-commit hooks of a released savepoint to its parent
+func (h *Hooks) Release() {
+	if h.parent == nil {
+		return
+	}
+
+	h.mu.Lock()
+	hooks := h.afterCommit
+	h.afterCommit = nil
+	h.mu.Unlock()
+
+	h.parent.mu.Lock()
+	defer h.parent.mu.Unlock()
+	h.parent.afterCommit = append(h.parent.afterCommit, hooks...)
+}
+
+// Discard drops after-commit hooks of a savepoint rolled back
+func (h *Hooks) Discard() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.afterCommit = nil
+}
+
+// Committed returns hooks to run after the transaction committed
+func (h *Hooks) Committed() []Hook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Hook(nil), h.afterCommit...)
+}
+
+// RolledBack returns hooks to run after the transaction rolled back
+func (h *Hooks) RolledBack() []Hook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Hook(nil), h.afterRollback...)
+}
+
+func InjectHooks(ctx context.Context, hooks *Hooks) context.Context {
+	return context.WithValue(ctx, HooksKey, hooks)
+}
+
+func ExtractHooks(ctx context.Context) (*Hooks, bool) {
+	if hooks, ok := ctx.Value(HooksKey).(*Hooks); ok {
+		return hooks, true
+	}
+	return nil, false
+}
@@ -2,12 +2,22 @@ package txctx
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/WithSoull/platform_common/pkg/contextx"
 	"github.com/jackc/pgx/v4"
 )
 
-const TxKey contextx.CtxKey = "tx"
+const (
+	TxKey        contextx.CtxKey = "tx"
+	SavepointKey contextx.CtxKey = "tx_savepoint"
+)
+
+// Savepoint describes a nested transaction opened inside the outer one
+type Savepoint struct {
+	Depth int    // Nesting level, 1 for the first nested transaction
+	Name  string // SQL identifier of the savepoint
+}
 
 func InjectTx(ctx context.Context, tx pgx.Tx) context.Context {
 	return context.WithValue(ctx, TxKey, tx)
@@ -19,3 +29,27 @@ func ExtractTx(ctx context.Context) (pgx.Tx, bool) {
 	}
 	return nil, false
 }
+
+// InjectSavepoint puts the next nested savepoint into the context and returns it
+func InjectSavepoint(ctx context.Context) (context.Context, Savepoint) {
+	depth := ExtractDepth(ctx) + 1
+	sp := Savepoint{
+		Depth: depth,
+		Name:  fmt.Sprintf("sp_%d", depth),
+	}
+
+	return context.WithValue(ctx, SavepointKey, sp), sp
+}
+
+func ExtractSavepoint(ctx context.Context) (Savepoint, bool) {
+	if sp, ok := ctx.Value(SavepointKey).(Savepoint); ok {
+		return sp, true
+	}
+	return Savepoint{}, false
+}
+
+// ExtractDepth returns the nesting level of the current transaction, 0 for the outermost one
+func ExtractDepth(ctx context.Context) int {
+	sp, _ := ExtractSavepoint(ctx)
+	return sp.Depth
+}
@@ -19,23 +19,44 @@ type consumer struct {
 	group       sarama.ConsumerGroup
 	topics      []string
 	logger      Logger
+	policy      FailurePolicy
 	middlewares []Middleware
 }
 
+// NewConsumer creates a consumer skipping failed messages, see DefaultFailurePolicy.
+// Use NewConsumerWithPolicy to retry them in place or stop the consumer instead.
 func NewConsumer(group sarama.ConsumerGroup, topics []string, logger Logger, middlewares ...Middleware) kafka.Consumer {
+	return NewConsumerWithPolicy(group, topics, logger, DefaultFailurePolicy(), middlewares...)
+}
+
+// NewConsumerWithPolicy creates a consumer handling handler errors according to policy
+func NewConsumerWithPolicy(group sarama.ConsumerGroup, topics []string, logger Logger, policy FailurePolicy, middlewares ...Middleware) kafka.Consumer {
 	return &consumer{
 		group:       group,
 		topics:      topics,
 		logger:      logger,
+		policy:      policy,
 		middlewares: middlewares,
 	}
 }
 
 func (c *consumer) Consume(ctx context.Context, handler kafka.MessageHandler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	newGroupHandler := NewGroupHandler(handler, c.logger, c.middlewares...)
+	newGroupHandler.policy = c.policy
+	newGroupHandler.stop = cancel
 
 	for {
-		if err := c.group.Consume(ctx, c.topics, newGroupHandler); err != nil {
+		err := c.group.Consume(ctx, c.topics, newGroupHandler)
+
+		// A handler error stops the consumer under the StopConsumer policy
+		if newGroupHandler.stopErr != nil {
+			return newGroupHandler.stopErr
+		}
+
+		if err != nil {
 			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
 				return nil
 			}
@@ -1,6 +1,10 @@
 package consumer
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/IBM/sarama"
 	"go.uber.org/zap"
 
@@ -14,6 +18,11 @@ type Middleware func(next kafka.MessageHandler) kafka.MessageHandler
 type groupHandler struct {
 	handler kafka.MessageHandler
 	logger  Logger
+	policy  FailurePolicy
+
+	stopOnce sync.Once
+	stopErr  error
+	stop     context.CancelFunc
 }
 
 // NewGroupHandler создаёт новый groupHandler с middleware цепочкой.
@@ -26,6 +35,7 @@ func NewGroupHandler(handler kafka.MessageHandler, logger Logger, middlewares ..
 	return &groupHandler{
 		handler: handler,
 		logger:  logger,
+		policy:  DefaultFailurePolicy(),
 	}
 }
 
@@ -57,9 +67,10 @@ func (g *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim s
 				Headers:        extractHeaders(message.Headers),
 			}
 
-			if err := g.handler(session.Context(), msg); err != nil {
-				g.logger.Error(session.Context(), "Kafka handler error", zap.Error(err))
-				continue
+			// The message is marked only when it is handled or the policy skips it,
+			// so the committed offset never moves past an unprocessed message
+			if !g.handle(session.Context(), msg) {
+				return nil
 			}
 
 			session.MarkMessage(message, "")
@@ -71,6 +82,61 @@ func (g *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim s
 	}
 }
 
+// handle runs the handler according to the failure policy and reports whether the message can be marked
+func (g *groupHandler) handle(ctx context.Context, msg kafka.Message) bool {
+	for attempt := 1; ; attempt++ {
+		err := g.handler(ctx, msg)
+		if err == nil {
+			return true
+		}
+		// The session ended during a rebalance or shutdown, the message is redelivered to the next owner
+		if ctx.Err() != nil {
+			return false
+		}
+
+		fields := []zap.Field{
+			zap.String("topic", msg.Topic),
+			zap.Int32("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		}
+
+		poison := g.policy.poison(err)
+		switch {
+		case g.policy.Action == StopConsumer:
+			g.logger.Error(ctx, "Kafka handler error, stopping consumer", fields...)
+			g.stopWith(err)
+			return false
+
+		case poison || g.policy.Action == SkipAndMark:
+			g.logger.Error(ctx, "Kafka handler error, message skipped", append(fields, zap.Bool("poison", poison))...)
+			return true
+		}
+
+		backoff := g.policy.backoff(attempt)
+		g.logger.Error(ctx, "Kafka handler error, retrying", append(fields, zap.Duration("backoff", backoff))...)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// stopWith records the first error that stops the consumer and ends the session
+func (g *groupHandler) stopWith(err error) {
+	g.stopOnce.Do(func() {
+		g.stopErr = err
+		if g.stop != nil {
+			g.stop()
+		}
+	})
+}
+
 func extractHeaders(headers []*sarama.RecordHeader) map[string][]byte {
 	result := make(map[string][]byte)
 	for _, h := range headers {
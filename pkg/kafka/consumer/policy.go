@@ -0,0 +1,94 @@
+package consumer
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FailureAction defines what the consumer does with a message the handler failed on
+type FailureAction int
+
+const (
+	// SkipAndMark logs the error, marks the message as consumed and moves on to the next one
+	SkipAndMark FailureAction = iota
+	// RetryInPlace re-runs the handler with backoff until it succeeds or the session ends,
+	// the partition does not move on meanwhile, so set IsPoison to skip messages that can never succeed
+	RetryInPlace
+	// StopConsumer leaves the message unmarked and returns the handler error from Consume
+	StopConsumer
+)
+
+const (
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 30 * time.Second
+)
+
+// FailurePolicy configures handling of handler errors.
+// Offsets are never committed past a failed message unless the policy skips it.
+type FailurePolicy struct {
+	Action FailureAction
+
+	// InitialBackoff and MaxBackoff bound the delay between RetryInPlace attempts
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// IsPoison reports errors the message can never be processed after, e.g. a decoding error.
+	// Poison messages are skipped and marked instead of retried, StopConsumer stops on them as well.
+	IsPoison func(err error) bool
+}
+
+// DefaultFailurePolicy skips failed messages after logging them, as consumers did before failure policies
+func DefaultFailurePolicy() FailurePolicy {
+	return FailurePolicy{
+		Action:         SkipAndMark,
+		InitialBackoff: defaultRetryInitialBackoff,
+		MaxBackoff:     defaultRetryMaxBackoff,
+	}
+}
+
+// PoisonErrors detects poison messages by errors.Is against any of targets
+func PoisonErrors(targets ...error) func(err error) bool {
+	return func(err error) bool {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PoisonType detects poison messages by errors.As against the error type T
+func PoisonType[T error]() func(err error) bool {
+	return func(err error) bool {
+		var target T
+		return errors.As(err, &target)
+	}
+}
+
+func (p FailurePolicy) poison(err error) bool {
+	return p.IsPoison != nil && p.IsPoison(err)
+}
+
+func (p FailurePolicy) backoff(attempt int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = defaultRetryInitialBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+
+	if attempt > 30 {
+		return maxBackoff
+	}
+
+	delay := initial << (attempt - 1)
+	if delay <= 0 || delay > maxBackoff {
+		return maxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,86 @@
+package event
+
+import (
+	"context"
+
+	"github.com/WithSoull/platform_common/pkg/kafka"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type Logger interface {
+	Debug(ctx context.Context, msg string, fields ...zap.Field)
+	Info(ctx context.Context, msg string, fields ...zap.Field)
+}
+
+// Publisher sends events of type T to the topic registered for it
+type Publisher[T proto.Message] struct {
+	sender kafka.MessageSender
+	logger Logger
+	topic  string
+	name   string
+}
+
+// NewPublisher resolves the topic of T in the registry
+func NewPublisher[T proto.Message](sender kafka.MessageSender, registry *Registry, logger Logger) (*Publisher[T], error) {
+	var zero T
+	name := TypeName(zero)
+
+	topic, ok := registry.Topic(name)
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownType, "no topic registered for %s", name)
+	}
+
+	return &Publisher[T]{
+		sender: sender,
+		logger: logger,
+		topic:  topic,
+		name:   string(name),
+	}, nil
+}
+
+// Publish marshals msg and sends it with the message type header
+func (p *Publisher[T]) Publish(ctx context.Context, key []byte, msg T) error {
+	value, err := proto.Marshal(msg)
+	if err != nil {
+		return errors.Wrapf(err, "marshal %s", p.name)
+	}
+
+	err = p.sender.SendMessage(ctx, kafka.Message{
+		Topic:   p.topic,
+		Key:     key,
+		Value:   value,
+		Headers: map[string][]byte{HeaderMessageType: []byte(p.name)},
+	})
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info(ctx, "Event published",
+		zap.String("type", p.name),
+		zap.String("topic", p.topic),
+		zap.ByteString("key", key),
+		zap.String("value_json", Pretty(msg)),
+	)
+	return nil
+}
+
+// Pretty renders msg as single-line JSON for logs
+func Pretty(msg proto.Message) string {
+	return protojson.MarshalOptions{EmitUnpopulated: true}.Format(msg)
+}
+
+// PrettyDecoder returns a kafka.PrettyDecoder rendering values of type T as JSON
+func PrettyDecoder[T proto.Message]() kafka.PrettyDecoder {
+	return func(value []byte) (string, bool) {
+		var zero T
+		msg := zero.ProtoReflect().New().Interface()
+		if err := proto.Unmarshal(value, msg); err != nil {
+			return "", false
+		}
+
+		return Pretty(msg), true
+	}
+}
@@ -0,0 +1,85 @@
+package event
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// HeaderMessageType carries the full protobuf name of the event, e.g. events.v1.UserCreated
+const HeaderMessageType = "x-message-type"
+
+var (
+	// ErrUnknownType is returned for events whose type is not registered or has no handler
+	ErrUnknownType = errors.New("event: unknown message type")
+	// ErrDecode is returned for events that cannot be unmarshalled into their type
+	ErrDecode = errors.New("event: cannot decode message")
+)
+
+// Registry maps event types to the topics they are published to
+type Registry struct {
+	mu     sync.RWMutex
+	topics map[protoreflect.FullName]string
+	types  map[string][]protoreflect.FullName
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		topics: make(map[protoreflect.FullName]string),
+		types:  make(map[string][]protoreflect.FullName),
+	}
+}
+
+// Register routes events of the type of msg to topic, msg may be a nil pointer, e.g. (*events_v1.UserCreated)(nil)
+func (r *Registry) Register(msg proto.Message, topic string) {
+	name := TypeName(msg)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if prev, ok := r.topics[name]; ok {
+		r.types[prev] = removeName(r.types[prev], name)
+	}
+	r.topics[name] = topic
+	r.types[topic] = append(r.types[topic], name)
+}
+
+// Topic returns the topic events of the type are published to
+func (r *Registry) Topic(name protoreflect.FullName) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	topic, ok := r.topics[name]
+	return topic, ok
+}
+
+// topicType returns the only type registered for the topic, it is used for messages without a type header
+func (r *Registry) topicType(topic string) (protoreflect.FullName, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := r.types[topic]
+	if len(names) != 1 {
+		return "", false
+	}
+
+	return names[0], true
+}
+
+// TypeName returns the full protobuf name of msg
+func TypeName(msg proto.Message) protoreflect.FullName {
+	return msg.ProtoReflect().Descriptor().FullName()
+}
+
+func removeName(names []protoreflect.FullName, name protoreflect.FullName) []protoreflect.FullName {
+	result := names[:0]
+	for _, n := range names {
+		if n != name {
+			result = append(result, n)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,96 @@
+package event
+
+import (
+	"context"
+	"sync"
+
+	"github.com/WithSoull/platform_common/pkg/kafka"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Handler processes a decoded event, msg carries the Kafka metadata of the record
+type Handler[T proto.Message] func(ctx context.Context, event T, msg kafka.Message) error
+
+// ErrorHandler decides what happens to an event of unknown type or that cannot be decoded,
+// returning nil skips it and an error leaves it to the consumer failure policy
+type ErrorHandler func(ctx context.Context, msg kafka.Message, err error) error
+
+// Subscriber decodes events by the message type header and dispatches them to typed handlers.
+// Messages without the header are decoded as the only type registered for their topic.
+type Subscriber struct {
+	registry *Registry
+	logger   Logger
+	onError  ErrorHandler
+
+	mu       sync.RWMutex
+	handlers map[protoreflect.FullName]func(ctx context.Context, msg kafka.Message) error
+}
+
+// NewSubscriber creates a subscriber returning ErrUnknownType and ErrDecode errors to the consumer,
+// see consumer.PoisonErrors to skip such messages
+func NewSubscriber(registry *Registry, logger Logger) *Subscriber {
+	return &Subscriber{
+		registry: registry,
+		logger:   logger,
+		onError: func(_ context.Context, _ kafka.Message, err error) error {
+			return err
+		},
+		handlers: make(map[protoreflect.FullName]func(ctx context.Context, msg kafka.Message) error),
+	}
+}
+
+// OnError replaces the handling of unknown and undecodable events
+func (s *Subscriber) OnError(fn ErrorHandler) *Subscriber {
+	s.onError = fn
+	return s
+}
+
+// Handle registers the handler of events of type T
+func Handle[T proto.Message](s *Subscriber, handler Handler[T]) {
+	var zero T
+	name := TypeName(zero)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[name] = func(ctx context.Context, msg kafka.Message) error {
+		event := zero.ProtoReflect().New().Interface().(T)
+		if err := proto.Unmarshal(msg.Value, event); err != nil {
+			return s.onError(ctx, msg, errors.Wrapf(ErrDecode, "%s: %v", name, err))
+		}
+
+		s.logger.Debug(ctx, "Event received",
+			zap.String("type", string(name)),
+			zap.String("topic", msg.Topic),
+			zap.Int32("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.String("value_json", Pretty(event)),
+		)
+
+		return handler(ctx, event, msg)
+	}
+}
+
+// Handler returns the kafka.MessageHandler to consume with
+func (s *Subscriber) Handler() kafka.MessageHandler {
+	return s.handle
+}
+
+func (s *Subscriber) handle(ctx context.Context, msg kafka.Message) error {
+	name := protoreflect.FullName(msg.Headers[HeaderMessageType])
+	if name == "" {
+		name, _ = s.registry.topicType(msg.Topic)
+	}
+
+	s.mu.RLock()
+	handler, ok := s.handlers[name]
+	s.mu.RUnlock()
+	if !ok {
+		return s.onError(ctx, msg, errors.Wrapf(ErrUnknownType, "type %q on topic %s", name, msg.Topic))
+	}
+
+	return handler(ctx, msg)
+}
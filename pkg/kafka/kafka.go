@@ -17,3 +17,9 @@ type PrettyDecoder func([]byte) (josn string, ok bool)
 type Producer interface {
 	Send(ctx context.Context, key, value []byte, prettyDecoder PrettyDecoder) error
 }
+
+// MessageSender sends a message to msg.Topic along with its headers,
+// e.g. to republish a consumed message to another topic
+type MessageSender interface {
+	SendMessage(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,350 @@
+package producer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/WithSoull/platform_common/pkg/kafka"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const defaultMaxInFlightBytes = 64 << 20
+
+// ErrProducerClosed is returned when a message is sent after Close
+var ErrProducerClosed = errors.New("kafka: producer is closed")
+
+type AsyncConfig interface {
+	// BatchMessages returns the number of messages that triggers a flush, 0 keeps the sarama default
+	BatchMessages() int
+	// BatchBytes returns the batch size in bytes that triggers a flush, 0 keeps the sarama default
+	BatchBytes() int
+	// Linger returns how long messages are buffered before a flush, 0 keeps the sarama default
+	Linger() time.Duration
+	// Compression returns none, gzip, snappy, lz4 or zstd, empty keeps the sarama default
+	Compression() string
+	// MaxInFlightBytes returns the size of unacknowledged messages after which sending blocks
+	MaxInFlightBytes() int64
+}
+
+// NewAsyncSaramaConfig returns sarama config with batching and compression from cfg,
+// delivery reports are enabled as required by AsyncProducer
+func NewAsyncSaramaConfig(cfg AsyncConfig) (*sarama.Config, error) {
+	sc := sarama.NewConfig()
+	sc.Producer.Return.Successes = true
+	sc.Producer.Return.Errors = true
+
+	if v := cfg.BatchMessages(); v > 0 {
+		sc.Producer.Flush.Messages = v
+	}
+	if v := cfg.BatchBytes(); v > 0 {
+		sc.Producer.Flush.Bytes = v
+	}
+	if v := cfg.Linger(); v > 0 {
+		sc.Producer.Flush.Frequency = v
+	}
+	if v := cfg.Compression(); v != "" {
+		if err := sc.Producer.Compression.UnmarshalText([]byte(v)); err != nil {
+			return nil, errors.Wrapf(err, "compression %q", v)
+		}
+	}
+
+	return sc, nil
+}
+
+// Delivery reports the outcome of an asynchronously sent message
+type Delivery struct {
+	Partition int32
+	Offset    int64
+	Err       error
+}
+
+// DeliveryCallback is called once the broker acknowledged or rejected the message
+type DeliveryCallback func(d Delivery)
+
+// AsyncProducer is a kafka.Producer on top of sarama.AsyncProducer:
+// SendAsync returns once the message is queued and reports delivery to a callback,
+// Send and SendMessage wait for the delivery report while messages of concurrent callers are batched.
+// Sending blocks while unacknowledged messages exceed MaxInFlightBytes.
+type AsyncProducer struct {
+	async  sarama.AsyncProducer
+	topic  string
+	logger Logger
+	flight *inFlight
+
+	closeMu sync.RWMutex
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// pending is attached to sarama.ProducerMessage.Metadata until the delivery report
+type pending struct {
+	ctx      context.Context
+	span     trace.Span
+	size     int64
+	callback DeliveryCallback
+}
+
+// NewAsyncProducer wraps asyncProducer, it must be created with NewAsyncSaramaConfig or report successes and errors.
+// Close drains pending messages, e.g. closer.AddNamed("kafka async producer", p.Close).
+func NewAsyncProducer(asyncProducer sarama.AsyncProducer, topic string, logger Logger, cfg AsyncConfig) *AsyncProducer {
+	limit := cfg.MaxInFlightBytes()
+	if limit <= 0 {
+		limit = defaultMaxInFlightBytes
+	}
+
+	p := &AsyncProducer{
+		async:  asyncProducer,
+		topic:  topic,
+		logger: logger,
+		flight: newInFlight(limit),
+	}
+
+	p.wg.Add(2)
+	go p.handleSuccesses()
+	go p.handleErrors()
+
+	return p
+}
+
+// Send sends the message to the producer topic and waits for the delivery report,
+// so that a nil error means the broker acknowledged the message as with the sync producer
+func (p *AsyncProducer) Send(ctx context.Context, key, value []byte, pretty kafka.PrettyDecoder) error {
+	delivered := make(chan error, 1)
+	err := p.SendAsync(ctx, kafka.Message{Topic: p.topic, Key: key, Value: value}, func(d Delivery) {
+		delivered <- d.Err
+		if d.Err != nil {
+			p.logger.Error(ctx, "Failed to send message", zap.String("topic", p.topic), zap.Error(d.Err))
+			return
+		}
+
+		fields := []zap.Field{
+			zap.Int32("partition", d.Partition),
+			zap.Int64("offset", d.Offset),
+			zap.ByteString("key", key),
+			zap.Int("value_size", len(value)),
+		}
+		if pretty != nil {
+			if js, ok := pretty(value); ok {
+				fields = append(fields, zap.String("value_json", js))
+			} else {
+				fields = append(fields, zap.Binary("value_raw", value))
+			}
+		} else {
+			fields = append(fields, zap.Binary("value_raw", value))
+		}
+		p.logger.Info(ctx, "Message sent", fields...)
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-delivered:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SendMessage sends msg to msg.Topic and waits for the delivery report
+func (p *AsyncProducer) SendMessage(ctx context.Context, msg kafka.Message) error {
+	delivered := make(chan error, 1)
+	if err := p.SendAsync(ctx, msg, func(d Delivery) { delivered <- d.Err }); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-delivered:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SendAsync queues msg to msg.Topic, or to the producer topic when it is empty.
+// callback may be nil, it is called from the delivery goroutine and must not block.
+// An error is returned only if the message was not queued.
+func (p *AsyncProducer) SendAsync(ctx context.Context, msg kafka.Message, callback DeliveryCallback) error {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	topic := msg.Topic
+	if topic == "" {
+		topic = p.topic
+	}
+
+	headers := make(map[string][]byte, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+
+	size := int64(len(msg.Key) + len(msg.Value))
+	for k, v := range headers {
+		size += int64(len(k) + len(v))
+	}
+
+	if err := p.flight.acquire(ctx, size); err != nil {
+		return err
+	}
+
+	ctx, span := startSendSpan(ctx, topic)
+	kafka.InjectHeaders(ctx, headers)
+
+	pm := &sarama.ProducerMessage{
+		Topic:   topic,
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: recordHeaders(headers),
+		Metadata: &pending{
+			ctx:      context.WithoutCancel(ctx),
+			span:     span,
+			size:     size,
+			callback: callback,
+		},
+	}
+	if msg.Key != nil {
+		pm.Key = sarama.ByteEncoder(msg.Key)
+	}
+
+	select {
+	case p.async.Input() <- pm:
+		return nil
+	case <-ctx.Done():
+		endSendSpan(span, 0, 0, ctx.Err())
+		span.End()
+		p.flight.release(size)
+		return ctx.Err()
+	}
+}
+
+// Flush waits until every queued message has been acknowledged or rejected
+func (p *AsyncProducer) Flush(ctx context.Context) error {
+	return p.flight.wait(ctx)
+}
+
+// Close stops accepting messages, drains pending ones and closes the underlying producer.
+// Messages still buffered when ctx is done are flushed by sarama on shutdown,
+// their callbacks are called before Close returns.
+func (p *AsyncProducer) Close(ctx context.Context) error {
+	p.closeMu.Lock()
+	if p.closed {
+		p.closeMu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.closeMu.Unlock()
+
+	flushErr := p.Flush(ctx)
+
+	p.async.AsyncClose()
+	p.wg.Wait()
+
+	return flushErr
+}
+
+func (p *AsyncProducer) handleSuccesses() {
+	defer p.wg.Done()
+
+	for pm := range p.async.Successes() {
+		p.deliver(pm, Delivery{Partition: pm.Partition, Offset: pm.Offset})
+	}
+}
+
+func (p *AsyncProducer) handleErrors() {
+	defer p.wg.Done()
+
+	for pe := range p.async.Errors() {
+		p.deliver(pe.Msg, Delivery{Err: pe.Err})
+	}
+}
+
+func (p *AsyncProducer) deliver(pm *sarama.ProducerMessage, d Delivery) {
+	pd, ok := pm.Metadata.(*pending)
+	if !ok {
+		return
+	}
+
+	endSendSpan(pd.span, d.Partition, d.Offset, d.Err)
+	pd.span.End()
+	p.flight.release(pd.size)
+
+	if pd.callback == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Error(pd.ctx, "Kafka delivery callback panic", zap.Any("panic", r))
+		}
+	}()
+	pd.callback(d)
+}
+
+// inFlight bounds the size of unacknowledged messages
+type inFlight struct {
+	mu    sync.Mutex
+	used  int64
+	count int
+	limit int64
+	freed chan struct{}
+}
+
+func newInFlight(limit int64) *inFlight {
+	return &inFlight{limit: limit, freed: make(chan struct{})}
+}
+
+// acquire blocks until size fits into the limit, a message larger than the limit waits for an empty queue
+func (f *inFlight) acquire(ctx context.Context, size int64) error {
+	for {
+		f.mu.Lock()
+		if f.count == 0 || f.used+size <= f.limit {
+			f.used += size
+			f.count++
+			f.mu.Unlock()
+			return nil
+		}
+		freed := f.freed
+		f.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-freed:
+		}
+	}
+}
+
+func (f *inFlight) release(size int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.used -= size
+	f.count--
+	close(f.freed)
+	f.freed = make(chan struct{})
+}
+
+// wait blocks until no messages are in flight
+func (f *inFlight) wait(ctx context.Context) error {
+	for {
+		f.mu.Lock()
+		if f.count == 0 {
+			f.mu.Unlock()
+			return nil
+		}
+		freed := f.freed
+		f.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-freed:
+		}
+	}
+}
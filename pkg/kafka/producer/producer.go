@@ -5,6 +5,10 @@ import (
 
 	"github.com/IBM/sarama"
 	"github.com/WithSoull/platform_common/pkg/kafka"
+	"github.com/WithSoull/platform_common/pkg/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -28,15 +32,24 @@ func NewProducer(syncProducer sarama.SyncProducer, topic string, logger Logger)
 }
 
 func (p *producer) Send(ctx context.Context, key, value []byte, pretty kafka.PrettyDecoder) error {
+	ctx, span := startSendSpan(ctx, p.topic)
+	defer span.End()
+
+	headers := make(map[string][]byte)
+	kafka.InjectHeaders(ctx, headers)
+
 	partition, offset, err := p.syncProducer.SendMessage(&sarama.ProducerMessage{
-		Topic: p.topic,
-		Key:   sarama.ByteEncoder(key),
-		Value: sarama.ByteEncoder(value),
+		Topic:   p.topic,
+		Key:     sarama.ByteEncoder(key),
+		Value:   sarama.ByteEncoder(value),
+		Headers: recordHeaders(headers),
 	})
 	if err != nil {
+		endSendSpan(span, 0, 0, err)
 		p.logger.Error(ctx, "Failed to send message", zap.Error(err))
 		return err
 	}
+	endSendSpan(span, partition, offset, nil)
 
 	fields := []zap.Field{
 		zap.Int32("partition", partition),
@@ -56,3 +69,83 @@ func (p *producer) Send(ctx context.Context, key, value []byte, pretty kafka.Pre
 	p.logger.Info(ctx, "Message sent", fields...)
 	return nil
 }
+
+// NewSender creates a kafka.MessageSender sending messages to their own topics
+func NewSender(syncProducer sarama.SyncProducer, logger Logger) kafka.MessageSender {
+	return &producer{
+		syncProducer: syncProducer,
+		logger:       logger,
+	}
+}
+
+func (p *producer) SendMessage(ctx context.Context, msg kafka.Message) error {
+	ctx, span := startSendSpan(ctx, msg.Topic)
+	defer span.End()
+
+	headers := make(map[string][]byte, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	// Republished messages keep the trace they belong to, so the current context is injected on top
+	kafka.InjectHeaders(ctx, headers)
+
+	pm := &sarama.ProducerMessage{
+		Topic:   msg.Topic,
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: recordHeaders(headers),
+	}
+	if msg.Key != nil {
+		pm.Key = sarama.ByteEncoder(msg.Key)
+	}
+
+	partition, offset, err := p.syncProducer.SendMessage(pm)
+	if err != nil {
+		endSendSpan(span, 0, 0, err)
+		p.logger.Error(ctx, "Failed to send message", zap.String("topic", msg.Topic), zap.Error(err))
+		return err
+	}
+	endSendSpan(span, partition, offset, nil)
+
+	p.logger.Info(ctx, "Message sent",
+		zap.String("topic", msg.Topic),
+		zap.Int32("partition", partition),
+		zap.Int64("offset", offset),
+		zap.ByteString("key", msg.Key),
+		zap.Int("value_size", len(msg.Value)),
+	)
+	return nil
+}
+
+// startSendSpan starts a producer span whose context is injected into the record headers
+func startSendSpan(ctx context.Context, topic string) (context.Context, trace.Span) {
+	return tracing.StartSpan(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem("kafka"),
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingOperationPublish,
+		),
+	)
+}
+
+func endSendSpan(span trace.Span, partition int32, offset int64, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	span.SetAttributes(
+		semconv.MessagingKafkaDestinationPartition(int(partition)),
+		semconv.MessagingKafkaMessageOffset(int(offset)),
+	)
+}
+
+func recordHeaders(headers map[string][]byte) []sarama.RecordHeader {
+	result := make([]sarama.RecordHeader, 0, len(headers))
+	for k, v := range headers {
+		result = append(result, sarama.RecordHeader{Key: []byte(k), Value: v})
+	}
+
+	return result
+}
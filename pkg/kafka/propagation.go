@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/WithSoull/platform_common/pkg/contextx/claimsctx"
+	"github.com/WithSoull/platform_common/pkg/contextx/ipctx"
+	traceidctx "github.com/WithSoull/platform_common/pkg/contextx/traceIDctx"
+	"go.opentelemetry.io/otel"
+)
+
+// Headers carrying request metadata from contextx between services
+const (
+	HeaderTraceID   = "x-trace-id"
+	HeaderUserID    = "x-user-id"
+	HeaderUserEmail = "x-user-email"
+	HeaderIP        = "x-ip"
+)
+
+// HeaderCarrier is an adapter between Kafka record headers and OpenTelemetry’s text map format.
+// Implements the TextMapCarrier interface for trace context propagation.
+type HeaderCarrier map[string][]byte
+
+func (hc HeaderCarrier) Get(key string) string {
+	return string(hc[key])
+}
+
+func (hc HeaderCarrier) Set(key, value string) {
+	hc[key] = []byte(value)
+}
+
+func (hc HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range hc {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// InjectHeaders writes the W3C trace context of ctx, via the global otel propagator,
+// and request metadata from contextx into headers
+func InjectHeaders(ctx context.Context, headers map[string][]byte) {
+	carrier := HeaderCarrier(headers)
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	if traceID, ok := traceidctx.ExtractTraceId(ctx); ok {
+		carrier.Set(HeaderTraceID, traceID)
+	}
+	if userID, ok := claimsctx.ExtractUserID(ctx); ok {
+		carrier.Set(HeaderUserID, strconv.FormatInt(userID, 10))
+	}
+	if email, ok := claimsctx.ExtractUserEmail(ctx); ok {
+		carrier.Set(HeaderUserEmail, email)
+	}
+	if ip, ok := ipctx.ExtractIP(ctx); ok {
+		carrier.Set(HeaderIP, ip)
+	}
+}
+
+// ExtractHeaders restores the trace context and request metadata written by InjectHeaders into ctx
+func ExtractHeaders(ctx context.Context, headers map[string][]byte) context.Context {
+	carrier := HeaderCarrier(headers)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+
+	if traceID := carrier.Get(HeaderTraceID); traceID != "" {
+		ctx = traceidctx.InjectTraceId(ctx, traceID)
+	}
+	if userID, err := strconv.ParseInt(carrier.Get(HeaderUserID), 10, 64); err == nil {
+		ctx = claimsctx.InjectUserID(ctx, userID)
+	}
+	if email := carrier.Get(HeaderUserEmail); email != "" {
+		ctx = claimsctx.InjectUserEmail(ctx, email)
+	}
+	if ip := carrier.Get(HeaderIP); ip != "" {
+		ctx = ipctx.InjectIpValue(ctx, ip)
+	}
+
+	return ctx
+}
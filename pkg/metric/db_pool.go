@@ -0,0 +1,113 @@
+package metric
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// DBPoolStats is a snapshot of connection pool statistics, counters are cumulative
+type DBPoolStats struct {
+	AcquiredConns   int64
+	IdleConns       int64
+	TotalConns      int64
+	MaxConns        int64
+	AcquireCount    int64
+	AcquireDuration time.Duration
+	EmptyAcquires   int64
+}
+
+// ErrDBPoolRegistered is returned when a pool with the same name and database is already registered
+var ErrDBPoolRegistered = errors.New("metric: db pool already registered")
+
+type dbPoolKey struct {
+	pool string
+	db   string
+}
+
+var (
+	dbPoolsMu sync.RWMutex
+	dbPools   = make(map[dbPoolKey]func() DBPoolStats)
+)
+
+// RegisterDBPool publishes stats of the named pool of database db on every metrics collection.
+// The returned func stops publishing, call it when the pool is closed.
+func RegisterDBPool(pool, db string, stats func() DBPoolStats) (unregister func(), err error) {
+	dbPoolsMu.Lock()
+	defer dbPoolsMu.Unlock()
+
+	key := dbPoolKey{pool: pool, db: db}
+	if _, ok := dbPools[key]; ok {
+		return nil, errors.Wrapf(ErrDBPoolRegistered, "pool %s of %s", pool, db)
+	}
+	dbPools[key] = stats
+
+	return func() {
+		dbPoolsMu.Lock()
+		defer dbPoolsMu.Unlock()
+		delete(dbPools, key)
+	}, nil
+}
+
+// initDBPoolMetrics creates observable instruments reporting registered pools
+func initDBPoolMetrics(serviceName string) error {
+	name := func(metricName string) string {
+		return fmt.Sprintf("db_%s_pool_%s", serviceName, metricName)
+	}
+
+	acquired, err := meter.Int64ObservableGauge(name("acquired_conns"))
+	if err != nil {
+		return err
+	}
+	idle, err := meter.Int64ObservableGauge(name("idle_conns"))
+	if err != nil {
+		return err
+	}
+	total, err := meter.Int64ObservableGauge(name("total_conns"))
+	if err != nil {
+		return err
+	}
+	maxConns, err := meter.Int64ObservableGauge(name("max_conns"))
+	if err != nil {
+		return err
+	}
+	acquires, err := meter.Int64ObservableCounter(name("acquires_total"))
+	if err != nil {
+		return err
+	}
+	acquireWait, err := meter.Float64ObservableCounter(name("acquire_wait_seconds_total"), metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	emptyAcquires, err := meter.Int64ObservableCounter(name("empty_acquires_total"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		dbPoolsMu.RLock()
+		defer dbPoolsMu.RUnlock()
+
+		for key, stats := range dbPools {
+			s := stats()
+			attrs := metric.WithAttributes(attribute.String("pool", key.pool), attribute.String("db", key.db))
+
+			o.ObserveInt64(acquired, s.AcquiredConns, attrs)
+			o.ObserveInt64(idle, s.IdleConns, attrs)
+			o.ObserveInt64(total, s.TotalConns, attrs)
+			o.ObserveInt64(maxConns, s.MaxConns, attrs)
+			o.ObserveInt64(acquires, s.AcquireCount, attrs)
+			o.ObserveFloat64(acquireWait, s.AcquireDuration.Seconds(), attrs)
+			o.ObserveInt64(emptyAcquires, s.EmptyAcquires, attrs)
+		}
+
+		return nil
+	}, acquired, idle, total, maxConns, acquires, acquireWait, emptyAcquires)
+
+	return err
+}
@@ -24,6 +24,10 @@ var (
 	requestCounter        metric.Int64Counter
 	responseCounter       metric.Int64Counter
 	histogramResponseTime metric.Float64Histogram
+
+	dbQueryCounter       metric.Int64Counter
+	dbQueryErrorCounter  metric.Int64Counter
+	histogramDBQueryTime metric.Float64Histogram
 )
 
 // Init инициализирует все инструменты метрик
@@ -56,7 +60,33 @@ func Init(_ context.Context, cfg MetricsConfig) error {
 		return err
 	}
 
-	return nil
+	dbQueryCounter, err = meter.Int64Counter(
+		fmt.Sprintf("db_%s_queries_total", cfg.ServiceName()),
+	)
+	if err != nil {
+		return err
+	}
+
+	dbQueryErrorCounter, err = meter.Int64Counter(
+		fmt.Sprintf("db_%s_query_errors_total", cfg.ServiceName()),
+	)
+	if err != nil {
+		return err
+	}
+
+	histogramDBQueryTime, err = meter.Float64Histogram(
+		fmt.Sprintf("db_%s_histogram_query_time_seconds", cfg.ServiceName()),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(
+			0.0001, 0.0002, 0.0004, 0.0008, 0.0016, 0.0032, 0.0064, 0.0128,
+			0.0256, 0.0512, 0.1024, 0.2048, 0.4096, 0.8192, 1.6384, 3.2768,
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	return initDBPoolMetrics(cfg.ServiceName())
 }
 
 func IncRequestCounter(ctx context.Context) {
@@ -80,6 +110,25 @@ func HistogramResponseTimeObserve(ctx context.Context, status string, time float
 	)
 }
 
+// ObserveDBQuery records duration and outcome of a named database query.
+// It is a no-op until Init has been called.
+func ObserveDBQuery(ctx context.Context, name, result string, time float64) {
+	if histogramDBQueryTime == nil {
+		return
+	}
+
+	attrs := metric.WithAttributes(
+		attribute.String("query", name),
+		attribute.String("result", result),
+	)
+
+	dbQueryCounter.Add(ctx, 1, attrs)
+	histogramDBQueryTime.Record(ctx, time, attrs)
+	if result != "success" {
+		dbQueryErrorCounter.Add(ctx, 1, attrs)
+	}
+}
+
 func InitOTELMetrics(cfg MetricsConfig) (*sdkmetric.MeterProvider, error) {
 	once.Do(func() {
 		meter = otel.Meter(cfg.ServiceName())
@@ -0,0 +1,150 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/WithSoull/platform_common/pkg/kafka"
+	"github.com/WithSoull/platform_common/pkg/kafka/consumer"
+	"go.uber.org/zap"
+)
+
+// Headers set on messages republished to retry and dead-letter topics
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderRetryAttempt      = "x-retry-attempt"
+	HeaderRetryNotBefore    = "x-retry-not-before" // unix milliseconds
+	HeaderError             = "x-error"
+)
+
+// RetryLogger reports republished messages and republish failures
+type RetryLogger interface {
+	Warn(ctx context.Context, msg string, fields ...zap.Field)
+	Error(ctx context.Context, msg string, fields ...zap.Field)
+}
+
+// RetryTopic is a tier of retries: failed messages are consumed from Topic again after Delay
+type RetryTopic struct {
+	Topic string
+	Delay time.Duration
+}
+
+// RetryPolicy lists retry tiers in order of attempts and the final dead-letter topic
+type RetryPolicy struct {
+	Topics          []RetryTopic
+	DeadLetterTopic string
+}
+
+// Retry republishes messages the handler failed on to the next retry topic of the policy,
+// and to the dead-letter topic once retries are exhausted.
+// A successfully republished message counts as handled, so its offset is committed.
+// Retry topics must be consumed with Delay placed before Retry in the middleware chain.
+func Retry(sender kafka.MessageSender, logger RetryLogger, policy RetryPolicy) consumer.Middleware {
+	return func(next kafka.MessageHandler) kafka.MessageHandler {
+		return func(ctx context.Context, msg kafka.Message) error {
+			handlerErr := next(ctx, msg)
+			if handlerErr == nil {
+				return nil
+			}
+
+			attempt := retryAttempt(msg)
+			out := kafka.Message{
+				Key:     msg.Key,
+				Value:   msg.Value,
+				Headers: retryHeaders(msg, attempt+1, handlerErr),
+			}
+
+			if attempt < len(policy.Topics) {
+				tier := policy.Topics[attempt]
+				out.Topic = tier.Topic
+				out.Headers[HeaderRetryNotBefore] = []byte(strconv.FormatInt(time.Now().Add(tier.Delay).UnixMilli(), 10))
+			} else {
+				out.Topic = policy.DeadLetterTopic
+				delete(out.Headers, HeaderRetryNotBefore)
+			}
+			// Without a dead-letter topic the message is left to the consumer failure handling
+			if out.Topic == "" {
+				return handlerErr
+			}
+
+			if err := sender.SendMessage(ctx, out); err != nil {
+				logger.Error(ctx, "Kafka failed to republish message",
+					zap.String("topic", out.Topic),
+					zap.Int("attempt", attempt+1),
+					zap.NamedError("handler_error", handlerErr),
+					zap.Error(err),
+				)
+				return handlerErr
+			}
+
+			logger.Warn(ctx, "Kafka message republished after handler error",
+				zap.String("topic", msg.Topic),
+				zap.String("republished_to", out.Topic),
+				zap.Int("attempt", attempt+1),
+				zap.Error(handlerErr),
+			)
+			return nil
+		}
+	}
+}
+
+// Delay holds a message consumed from a retry topic until its retry time comes.
+// Retry topics keep a single delay each, so waiting does not reorder the partition.
+func Delay() consumer.Middleware {
+	return func(next kafka.MessageHandler) kafka.MessageHandler {
+		return func(ctx context.Context, msg kafka.Message) error {
+			raw, ok := msg.Headers[HeaderRetryNotBefore]
+			if !ok {
+				return next(ctx, msg)
+			}
+
+			notBefore, err := strconv.ParseInt(string(raw), 10, 64)
+			if err != nil {
+				return next(ctx, msg)
+			}
+
+			if wait := time.Until(time.UnixMilli(notBefore)); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+
+			return next(ctx, msg)
+		}
+	}
+}
+
+// retryAttempt returns how many times the message has already been retried
+func retryAttempt(msg kafka.Message) int {
+	attempt, err := strconv.Atoi(string(msg.Headers[HeaderRetryAttempt]))
+	if err != nil || attempt < 0 {
+		return 0
+	}
+
+	return attempt
+}
+
+// retryHeaders copies headers of msg, keeping the original coordinates set on the first failure
+func retryHeaders(msg kafka.Message, attempt int, handlerErr error) map[string][]byte {
+	headers := make(map[string][]byte, len(msg.Headers)+5)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+
+	if _, ok := headers[HeaderOriginalTopic]; !ok {
+		headers[HeaderOriginalTopic] = []byte(msg.Topic)
+		headers[HeaderOriginalPartition] = []byte(strconv.FormatInt(int64(msg.Partition), 10))
+		headers[HeaderOriginalOffset] = []byte(strconv.FormatInt(msg.Offset, 10))
+	}
+	headers[HeaderRetryAttempt] = []byte(strconv.Itoa(attempt))
+	headers[HeaderError] = []byte(handlerErr.Error())
+
+	return headers
+}
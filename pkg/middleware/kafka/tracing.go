@@ -0,0 +1,48 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/WithSoull/platform_common/pkg/kafka"
+	"github.com/WithSoull/platform_common/pkg/kafka/consumer"
+	"github.com/WithSoull/platform_common/pkg/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing restores the trace context and request metadata sent in message headers
+// and runs the handler within a consumer span continuing the producer trace.
+// It goes first in the middleware chain, so that logs of the following ones carry the restored fields.
+func Tracing() consumer.Middleware {
+	return func(next kafka.MessageHandler) kafka.MessageHandler {
+		return func(ctx context.Context, msg kafka.Message) error {
+			ctx = kafka.ExtractHeaders(ctx, msg.Headers)
+
+			opts := []trace.SpanStartOption{
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					semconv.MessagingSystem("kafka"),
+					semconv.MessagingSourceName(msg.Topic),
+					semconv.MessagingKafkaSourcePartition(int(msg.Partition)),
+					semconv.MessagingKafkaMessageOffset(int(msg.Offset)),
+					semconv.MessagingOperationProcess,
+				),
+			}
+			if producer := trace.SpanContextFromContext(ctx); producer.IsValid() {
+				opts = append(opts, trace.WithLinks(trace.Link{SpanContext: producer}))
+			}
+
+			ctx, span := tracing.StartSpan(ctx, msg.Topic+" process", opts...)
+			defer span.End()
+
+			err := next(ctx, msg)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			return err
+		}
+	}
+}
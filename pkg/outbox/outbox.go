@@ -0,0 +1,101 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/contextx/txctx"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultTable is the outbox table name used when none is configured
+const DefaultTable = "outbox"
+
+// ErrNoTransaction is returned when an event is added outside a TxManager transaction
+var ErrNoTransaction = errors.New("outbox: event must be added inside a transaction")
+
+// Outbox stores events in the same transaction as the business data they describe
+type Outbox interface {
+	// Add stores a serialized event for the topic
+	Add(ctx context.Context, topic string, key, payload []byte) error
+	// AddProto marshals msg and stores it for the topic
+	AddProto(ctx context.Context, topic string, key []byte, msg proto.Message) error
+}
+
+type outbox struct {
+	db    db.DB
+	query db.Query
+}
+
+// NewOutbox creates an outbox writing into table, DefaultTable is used when table is empty
+func NewOutbox(dbc db.DB, table string) Outbox {
+	return &outbox{
+		db: dbc,
+		query: db.Query{
+			Name:     "outbox.add",
+			QueryRaw: fmt.Sprintf(`INSERT INTO %s (topic, key, payload) VALUES ($1, $2, $3)`, tableIdentifier(table)),
+		},
+	}
+}
+
+func (o *outbox) Add(ctx context.Context, topic string, key, payload []byte) error {
+	if _, ok := txctx.ExtractTx(ctx); !ok {
+		return ErrNoTransaction
+	}
+
+	if _, err := o.db.ExecContext(ctx, o.query, topic, key, payload); err != nil {
+		return errors.Wrap(err, "outbox: add event")
+	}
+
+	return nil
+}
+
+func (o *outbox) AddProto(ctx context.Context, topic string, key []byte, msg proto.Message) error {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "outbox: marshal event")
+	}
+
+	return o.Add(ctx, topic, key, payload)
+}
+
+// Schema returns DDL creating the outbox table and its indexes of pending events
+func Schema(table string) string {
+	ident := tableIdentifier(table)
+	prefix := strings.ReplaceAll(tableName(table), ".", "_")
+	index := pgx.Identifier{prefix + "_pending_idx"}.Sanitize()
+	keyIndex := pgx.Identifier{prefix + "_pending_key_idx"}.Sanitize()
+
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+	id              BIGSERIAL PRIMARY KEY,
+	topic           TEXT        NOT NULL,
+	key             BYTEA,
+	payload         BYTEA       NOT NULL,
+	attempts        INT         NOT NULL DEFAULT 0,
+	last_error      TEXT,
+	created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	sent_at         TIMESTAMPTZ,
+	failed_at       TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (next_attempt_at, id) WHERE sent_at IS NULL AND failed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS %[3]s ON %[1]s (topic, key, id) WHERE sent_at IS NULL AND failed_at IS NULL;`, ident, index, keyIndex)
+}
+
+func tableName(table string) string {
+	if table == "" {
+		return DefaultTable
+	}
+	return table
+}
+
+// tableIdentifier quotes an optionally schema qualified table name
+func tableIdentifier(table string) string {
+	return pgx.Identifier(strings.Split(tableName(table), ".")).Sanitize()
+}
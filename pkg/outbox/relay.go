@@ -0,0 +1,337 @@
+package outbox
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/WithSoull/platform_common/pkg/client/db"
+	"github.com/WithSoull/platform_common/pkg/client/db/lock"
+	"github.com/WithSoull/platform_common/pkg/closer"
+	"github.com/WithSoull/platform_common/pkg/kafka"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPollInterval    = time.Second
+	defaultBatchSize       = 100
+	defaultMaxAttempts     = 10
+	defaultRetryBackoff    = time.Second
+	defaultCleanupInterval = time.Hour
+	maxRetryBackoff        = 10 * time.Minute
+)
+
+type Logger interface {
+	Info(ctx context.Context, msg string, fields ...zap.Field)
+	Error(ctx context.Context, msg string, fields ...zap.Field)
+}
+
+// RelayConfig configures polling, retry and cleanup policies of the relay.
+// Zero values fall back to defaults, a zero Retention keeps sent events forever.
+type RelayConfig interface {
+	Table() string
+	PollInterval() time.Duration
+	BatchSize() int
+	MaxAttempts() int
+	RetryBackoff() time.Duration
+	Retention() time.Duration
+	CleanupInterval() time.Duration
+}
+
+type event struct {
+	ID       int64  `db:"id"`
+	Topic    string `db:"topic"`
+	Key      []byte `db:"key"`
+	Payload  []byte `db:"payload"`
+	Attempts int    `db:"attempts"`
+}
+
+// Relay publishes pending outbox events to Kafka and marks them sent
+type Relay struct {
+	db        db.DB
+	txManager db.TxManager
+	locker    *lock.Locker
+	producers map[string]kafka.Producer
+	logger    Logger
+	cfg       RelayConfig
+	table     string
+
+	fetchQuery   db.Query
+	blockedQuery db.Query
+	sentQuery    db.Query
+	failedQuery  db.Query
+	cleanupQuery db.Query
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	startOnce    sync.Once
+}
+
+// NewRelay creates a relay publishing events through the producer registered for their topic
+func NewRelay(dbc db.DB, txManager db.TxManager, producers map[string]kafka.Producer, logger Logger, cfg RelayConfig) *Relay {
+	table := tableIdentifier(cfg.Table())
+
+	return &Relay{
+		db:        dbc,
+		txManager: txManager,
+		locker:    lock.NewLocker(dbc),
+		producers: producers,
+		logger:    logger,
+		cfg:       cfg,
+		table:     tableName(cfg.Table()),
+		fetchQuery: db.Query{
+			Name: "outbox.fetch",
+			// An event waits while an earlier event with the same topic and key is being retried,
+			// so that the retried event is not overtaken by later events of its partition
+			QueryRaw: fmt.Sprintf(`SELECT id, topic, key, payload, attempts FROM %[1]s e
+				WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= now()
+					AND NOT EXISTS (
+						SELECT 1 FROM %[1]s p
+						WHERE p.topic = e.topic AND p.key = e.key AND p.id < e.id
+							AND p.sent_at IS NULL AND p.failed_at IS NULL AND p.attempts > 0
+					)
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED`, table),
+		},
+		blockedQuery: db.Query{
+			Name: "outbox.key_blocked",
+			QueryRaw: fmt.Sprintf(`SELECT EXISTS (
+				SELECT 1 FROM %s
+				WHERE topic = $1 AND key = $2 AND id < $3 AND sent_at IS NULL AND failed_at IS NULL
+			)`, table),
+		},
+		sentQuery: db.Query{
+			Name:     "outbox.mark_sent",
+			QueryRaw: fmt.Sprintf(`UPDATE %s SET sent_at = now() WHERE id = ANY($1)`, table),
+		},
+		failedQuery: db.Query{
+			Name: "outbox.mark_failed",
+			QueryRaw: fmt.Sprintf(`UPDATE %s SET
+				attempts = attempts + 1,
+				last_error = $2,
+				next_attempt_at = now() + make_interval(secs => $3),
+				failed_at = CASE WHEN attempts + 1 >= $4 THEN now() END
+				WHERE id = $1`, table),
+		},
+		cleanupQuery: db.Query{
+			Name:     "outbox.cleanup",
+			QueryRaw: fmt.Sprintf(`DELETE FROM %s WHERE sent_at < now() - make_interval(secs => $1)`, table),
+		},
+	}
+}
+
+// Start runs the relay in the background and registers it in the global closer
+func (r *Relay) Start(ctx context.Context) {
+	r.startOnce.Do(func() {
+		ctx, r.cancel = context.WithCancel(ctx)
+
+		r.wg.Add(1)
+		go r.run(ctx)
+
+		closer.AddNamed("outbox relay", r.Close)
+	})
+}
+
+// Close stops polling and waits for the in-flight batch to finish
+func (r *Relay) Close(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Relay) run(ctx context.Context) {
+	defer r.wg.Done()
+
+	poll := time.NewTicker(durationOr(r.cfg.PollInterval(), defaultPollInterval))
+	defer poll.Stop()
+
+	cleanup := time.NewTicker(durationOr(r.cfg.CleanupInterval(), defaultCleanupInterval))
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			// Drain the backlog without waiting for the next tick while full batches make progress
+			for {
+				more, err := r.publishBatch(ctx)
+				if err != nil {
+					r.logger.Error(ctx, "outbox relay batch failed", zap.Error(err))
+					break
+				}
+				if !more || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-cleanup.C:
+			if err := r.cleanup(ctx); err != nil {
+				r.logger.Error(ctx, "outbox cleanup failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// publishBatch locks a batch of pending events and publishes them in id order.
+// Events with the same topic and key are published in order: after a failure the rest of its key
+// is held back until the failed event is sent or exhausts its attempts, other keys go on.
+// A key is claimed with a transaction-level advisory lock, so concurrent relays do not interleave its events.
+// Events without a key are not ordered.
+// It reports whether a full batch made progress and more events may be pending.
+func (r *Relay) publishBatch(ctx context.Context) (bool, error) {
+	var more bool
+
+	err := r.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		var events []event
+		if err := r.db.ScanAllContext(ctx, &events, r.fetchQuery, r.batchSize()); err != nil {
+			return err
+		}
+
+		sent := make([]int64, 0, len(events))
+		claimed := make(map[string]bool)
+		blocked := make(map[string]bool)
+		failed := 0
+		for _, e := range events {
+			key := orderKey(e)
+			if e.Key != nil {
+				if blocked[key] {
+					continue
+				}
+				if !claimed[key] {
+					ok, err := r.claimKey(ctx, e)
+					if err != nil {
+						return err
+					}
+					if !ok {
+						blocked[key] = true
+						continue
+					}
+					claimed[key] = true
+				}
+			}
+
+			if err := r.publish(ctx, e); err != nil {
+				r.logger.Error(ctx, "outbox event publish failed",
+					zap.Int64("id", e.ID),
+					zap.String("topic", e.Topic),
+					zap.Int("attempts", e.Attempts+1),
+					zap.Error(err),
+				)
+
+				if _, err := r.db.ExecContext(ctx, r.failedQuery, e.ID, err.Error(), r.backoff(e.Attempts).Seconds(), r.maxAttempts()); err != nil {
+					return err
+				}
+				failed++
+				blocked[key] = true
+				continue
+			}
+
+			sent = append(sent, e.ID)
+		}
+
+		more = len(events) == r.batchSize() && len(sent)+failed > 0
+		if len(sent) == 0 {
+			return nil
+		}
+
+		_, err := r.db.ExecContext(ctx, r.sentQuery, sent)
+		return err
+	})
+
+	return more, err
+}
+
+// claimKey locks the topic and key of e for the transaction and reports whether e is the earliest unsent event of it.
+// The check after locking catches earlier events fetched by another relay that has not committed yet.
+func (r *Relay) claimKey(ctx context.Context, e event) (bool, error) {
+	ok, err := r.locker.TryAcquireTx(ctx, lock.Key("outbox:"+r.table+":"+orderKey(e)))
+	if err != nil || !ok {
+		return false, err
+	}
+
+	var blocked bool
+	if err := r.db.QueryRowContext(ctx, r.blockedQuery, e.Topic, e.Key, e.ID).Scan(&blocked); err != nil {
+		return false, err
+	}
+
+	return !blocked, nil
+}
+
+// orderKey identifies the events that must be published in order
+func orderKey(e event) string {
+	return e.Topic + ":" + hex.EncodeToString(e.Key)
+}
+
+func (r *Relay) publish(ctx context.Context, e event) error {
+	producer, ok := r.producers[e.Topic]
+	if !ok {
+		return fmt.Errorf("no producer registered for topic %q", e.Topic)
+	}
+
+	return producer.Send(ctx, e.Key, e.Payload, nil)
+}
+
+func (r *Relay) cleanup(ctx context.Context) error {
+	retention := r.cfg.Retention()
+	if retention <= 0 {
+		return nil
+	}
+
+	tag, err := r.db.ExecContext(ctx, r.cleanupQuery, retention.Seconds())
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() > 0 {
+		r.logger.Info(ctx, "outbox cleanup removed sent events", zap.Int64("count", tag.RowsAffected()))
+	}
+
+	return nil
+}
+
+// backoff returns the exponential delay before the next attempt of an event
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := durationOr(r.cfg.RetryBackoff(), defaultRetryBackoff) << attempts
+	if delay <= 0 || delay > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+
+	return delay
+}
+
+func (r *Relay) batchSize() int {
+	if r.cfg.BatchSize() <= 0 {
+		return defaultBatchSize
+	}
+	return r.cfg.BatchSize()
+}
+
+func (r *Relay) maxAttempts() int {
+	if r.cfg.MaxAttempts() <= 0 {
+		return defaultMaxAttempts
+	}
+	return r.cfg.MaxAttempts()
+}
+
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}